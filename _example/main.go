@@ -4,16 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
 	"reflect"
 
 	"github.com/InVisionApp/conjungo"
-	log "github.com/sirupsen/logrus"
 )
 
-func init() {
-	log.SetLevel(log.InfoLevel)
-}
-
 func main() {
 	fmt.Println("Simple map merge")
 	SimpleMap()
@@ -56,7 +52,7 @@ func SimpleMap() {
 
 	err := conjungo.Merge(&targetMap, sourceMap, nil)
 	if err != nil {
-		log.Error(err)
+		log.Println(err)
 	}
 
 	marshalIndentPrint(targetMap)
@@ -86,7 +82,7 @@ func SimpleStruct() {
 
 	err := conjungo.Merge(&targetStruct, sourceStruct, nil)
 	if err != nil {
-		log.Error(err)
+		log.Println(err)
 	}
 
 	marshalIndentPrint(targetStruct)
@@ -131,7 +127,7 @@ func CustomMerge() {
 
 	err := conjungo.Merge(&targetMap, sourceMap, opts)
 	if err != nil {
-		log.Error(err)
+		log.Println(err)
 	}
 
 	marshalIndentPrint(targetMap)
@@ -172,7 +168,7 @@ func CustomStructMerge() {
 
 	err := conjungo.Merge(&target, source, opts)
 	if err != nil {
-		log.Error(err)
+		log.Println(err)
 	}
 
 	marshalIndentPrint(target)
@@ -195,7 +191,7 @@ func NoOverwrite() {
 	opts.Overwrite = false
 	err := conjungo.Merge(&targetMap, sourceMap, opts)
 	if err != nil {
-		log.Error(err)
+		log.Println(err)
 	}
 
 	marshalIndentPrint(targetMap)
@@ -256,7 +252,7 @@ func FromJSON() {
 
 	err := conjungo.Merge(&targetJSON, sourceJSON, opts)
 	if err != nil {
-		log.Error(err)
+		log.Println(err)
 	}
 
 	fmt.Println(targetJSON)
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// fieldKind determines the code generated to merge a field.
+type fieldKind int
+
+const (
+	// builtin scalar types, merged according to Overwrite
+	kindScalar fieldKind = iota
+
+	// slices, appended
+	kindSlice
+
+	// maps of builtin scalar values, merged key by key
+	kindMap
+
+	// types generated in the same run, merged with their generated func
+	kindGenerated
+
+	// anything else, merged with conjungo.Merge
+	kindOther
+)
+
+var scalarTypes = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true, "uintptr": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+}
+
+type structInfo struct {
+	Name   string
+	Fields []fieldInfo
+
+	// expressions for the types of the fields merged by the generated code itself, which
+	// are only merged so while they have the default merge funcs
+	Types []string
+
+	Unexported    []fieldInfo
+	HasUnexported bool
+}
+
+type fieldInfo struct {
+	// the Go name of the field
+	Name string
+
+	// the selector for the field from a value of the generated type, which differs from
+	// its name for fields promoted from unexported embedded structs
+	Path string
+
+	// the name of the struct type declaring the field
+	Struct string
+
+	// the name of the field in errors, from its `conjungo` tag
+	Label string
+
+	Kind     fieldKind
+	Exported bool
+
+	// the key and value types of maps, and the type of generated fields
+	KeyType, ValueType string
+}
+
+// generate generates merge funcs for the named struct types declared in the package in dir.
+// The args are those the command was run with, which are recorded in the generated code.
+func generate(dir string, typeNames []string, args string) ([]byte, error) {
+	pkgName, specs, err := parseStructs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := map[string]bool{}
+	for _, name := range typeNames {
+		generated[name] = true
+	}
+
+	var structs []structInfo
+	for _, name := range typeNames {
+		spec, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+
+		info, err := newStructInfo(spec, specs, generated)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, info)
+	}
+
+	needFmt := false
+	for _, s := range structs {
+		for _, f := range s.Fields {
+			needFmt = needFmt || f.Kind == kindGenerated || f.Kind == kindOther
+		}
+		needFmt = needFmt || s.HasUnexported
+	}
+
+	var buf bytes.Buffer
+	err = fileTemplate.Execute(&buf, map[string]interface{}{
+		"Args":    args,
+		"Package": pkgName,
+		"Structs": structs,
+		"NeedFmt": needFmt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %v", err)
+	}
+
+	return src, nil
+}
+
+// parseStructs parses the non-test Go files in dir, and returns the name of the package
+// along with its struct type declarations by name.
+func parseStructs(dir string) (string, map[string]*ast.TypeSpec, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	pkgName := ""
+	specs := map[string]*ast.TypeSpec{}
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || strings.HasSuffix(file, "_conjungo.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if pkgName == "" {
+			pkgName = f.Name.Name
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if _, ok := spec.Type.(*ast.StructType); ok {
+					specs[spec.Name.Name] = spec
+				}
+			}
+			return true
+		})
+	}
+
+	if pkgName == "" {
+		return "", nil, fmt.Errorf("no Go files found in %s", dir)
+	}
+
+	return pkgName, specs, nil
+}
+
+// newStructInfo collects the fields of the struct type, including those promoted from
+// unexported embedded structs declared in the same package, which conjungo merges like the
+// fields of the struct itself.
+func newStructInfo(spec *ast.TypeSpec, specs map[string]*ast.TypeSpec, generated map[string]bool) (structInfo, error) {
+	info := structInfo{Name: spec.Name.Name}
+	seenTypes := map[string]bool{}
+	addType := func(key, expr string) {
+		if !seenTypes[key] {
+			seenTypes[key] = true
+			info.Types = append(info.Types, expr)
+		}
+	}
+
+	if spec.TypeParams != nil && len(spec.TypeParams.List) > 0 {
+		return info, fmt.Errorf("generic type %s is not supported", info.Name)
+	}
+
+	var addFields func(structName, prefix string, st *ast.StructType) error
+	addFields = func(structName, prefix string, st *ast.StructType) error {
+		for _, field := range st.Fields.List {
+			names := field.Names
+			if len(names) == 0 {
+				// embedded fields are named after their type
+				names = []*ast.Ident{ast.NewIdent(embeddedName(field.Type))}
+			}
+
+			label := ""
+			hasStrategy := false
+			if field.Tag != nil {
+				tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+				parts := strings.Split(tag.Get("conjungo"), ",")
+				label = parts[0]
+
+				for _, opt := range parts[1:] {
+					hasStrategy = hasStrategy || strings.HasPrefix(opt, "strategy=")
+				}
+			}
+
+			for _, name := range names {
+				if name.Name == "_" {
+					continue
+				}
+
+				if hasStrategy {
+					return fmt.Errorf("field %s.%s has a merge strategy in its tag, which is not supported",
+						structName, name.Name)
+				}
+
+				if embedded := embeddedStruct(field, specs); embedded != nil && !name.IsExported() {
+					err := addFields(embedded.Name.Name, prefix+name.Name+".", embedded.Type.(*ast.StructType))
+					if err != nil {
+						return err
+					}
+					continue
+				}
+
+				f := fieldInfo{
+					Name:     name.Name,
+					Path:     prefix + name.Name,
+					Struct:   structName,
+					Label:    name.Name,
+					Exported: name.IsExported(),
+				}
+				if label != "" && label != "-" {
+					f.Label = label
+				}
+
+				f.Kind, f.KeyType, f.ValueType = classify(field.Type, generated)
+				if len(field.Names) == 0 && f.Kind != kindGenerated {
+					// embedded struct pointers are merged unlike other pointers, so leave
+					// embedded fields to conjungo
+					f.Kind = kindOther
+				}
+
+				fieldType := fmt.Sprintf("reflect.TypeOf(%s{}.%s)", info.Name, f.Path)
+				switch f.Kind {
+				case kindScalar, kindSlice:
+					addType(types.ExprString(field.Type), fieldType)
+				case kindMap:
+					addType(types.ExprString(field.Type), fieldType)
+					addType(f.ValueType, fieldType+".Elem()")
+				}
+
+				if !f.Exported {
+					info.HasUnexported = true
+					info.Unexported = append(info.Unexported, f)
+				}
+
+				info.Fields = append(info.Fields, f)
+			}
+		}
+
+		return nil
+	}
+
+	err := addFields(info.Name, "", spec.Type.(*ast.StructType))
+	return info, err
+}
+
+// embeddedStruct returns the declaration of the struct type embedded by the field, if it is
+// embedded by value and declared in the package without type parameters.
+func embeddedStruct(field *ast.Field, specs map[string]*ast.TypeSpec) *ast.TypeSpec {
+	if len(field.Names) > 0 {
+		return nil
+	}
+
+	ident, ok := field.Type.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	spec, ok := specs[ident.Name]
+	if !ok || (spec.TypeParams != nil && len(spec.TypeParams.List) > 0) {
+		return nil
+	}
+
+	return spec
+}
+
+// classify determines how a field of the given type is merged.
+func classify(expr ast.Expr, generated map[string]bool) (fieldKind, string, string) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if scalarTypes[t.Name] {
+			return kindScalar, "", ""
+		}
+		if generated[t.Name] {
+			return kindGenerated, "", t.Name
+		}
+
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return kindSlice, "", ""
+		}
+
+	case *ast.MapType:
+		if v, ok := t.Value.(*ast.Ident); ok && scalarTypes[v.Name] {
+			return kindMap, types.ExprString(t.Key), v.Name
+		}
+	}
+
+	return kindOther, "", ""
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return types.ExprString(expr)
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"scalar":    func(k fieldKind) bool { return k == kindScalar },
+	"slice":     func(k fieldKind) bool { return k == kindSlice },
+	"map":       func(k fieldKind) bool { return k == kindMap },
+	"generated": func(k fieldKind) bool { return k == kindGenerated },
+	"join":      strings.Join,
+}).Parse(`// Code generated by conjungo-gen {{.Args}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	{{if .NeedFmt}}"fmt"{{end}}
+	"reflect"
+
+	"github.com/InVisionApp/conjungo"
+)
+{{range .Structs}}
+{{if .Types}}
+// conjungo{{.Name}}Types holds the types of the fields Merge{{.Name}} merges itself.
+var conjungo{{.Name}}Types = []reflect.Type{
+	{{range .Types}}{{.}},
+	{{end}}
+}
+{{end}}
+// Merge{{.Name}} merges src onto dst like conjungo.Merge does. If the options set hooks, a
+// policy, limits or merge funcs for the types of its fields, the fields are merged by conjungo
+// instead. If an error occurs, dst is left unmodified.
+func Merge{{.Name}}(dst *{{.Name}}, src {{.Name}}, o *conjungo.Options) error {
+	if o == nil {
+		o = conjungo.NewOptions()
+	}
+
+	if o.Context != nil {
+		if err := o.Context.Err(); err != nil {
+			return err
+		}
+	}
+
+	if o.RequiresReflection(){{if .Types}} || !o.UsesDefaultMergeFuncs(conjungo{{.Name}}Types...){{end}} {
+		merged, err := conjungo.MergeStruct(reflect.ValueOf(*dst), reflect.ValueOf(src), o)
+		if err != nil {
+			return err
+		}
+		*dst = merged.Interface().({{.Name}})
+		return nil
+	}
+{{if .HasUnexported}}
+	switch {
+	case o.ErrorOnUnexported:
+		{{with index .Unexported 0}}return fmt.Errorf("struct of type {{.Struct}} has unexported field: %s", {{printf "%q" .Name}}){{end}}
+
+	case !o.MergeUnexported && !o.SkipUnexported:
+		// treat the struct as a single entity
+		if o.Overwrite {
+			*dst = src
+		}
+		return nil
+	}
+{{end}}
+	merged := *dst
+{{range .Fields}}
+	{{if not .Exported}}if o.MergeUnexported {
+	{{end}}{{if scalar .Kind}}if o.Overwrite {
+		merged.{{.Path}} = src.{{.Path}}
+	}
+	{{else if slice .Kind}}if merged.{{.Path}} == nil {
+		merged.{{.Path}} = src.{{.Path}}
+	} else if src.{{.Path}} != nil {
+		merged.{{.Path}} = append(merged.{{.Path}}, src.{{.Path}}...)
+	}
+	{{else if map .Kind}}if merged.{{.Path}} == nil {
+		merged.{{.Path}} = src.{{.Path}}
+	} else if src.{{.Path}} != nil {
+		m := make(map[{{.KeyType}}]{{.ValueType}}, len(merged.{{.Path}}))
+		for k, v := range merged.{{.Path}} {
+			m[k] = v
+		}
+		for k, v := range src.{{.Path}} {
+			if _, ok := m[k]; !ok || o.Overwrite {
+				m[k] = v
+			}
+		}
+		merged.{{.Path}} = m
+	}
+	{{else if generated .Kind}}if !o.UsesDefaultMergeFuncs(reflect.TypeOf(src.{{.Path}})) {
+		// merged with the func set for the type
+		if err := conjungo.Merge(&merged.{{.Path}}, &src.{{.Path}}, o); err != nil {
+			return fmt.Errorf("failed to merge field ` + "`" + `{{.Struct}}.{{.Label}}` + "`" + `: %w", err)
+		}
+	} else if err := Merge{{.ValueType}}(&merged.{{.Path}}, src.{{.Path}}, o); err != nil {
+		return fmt.Errorf("failed to merge field ` + "`" + `{{.Struct}}.{{.Label}}` + "`" + `: %w", err)
+	}
+	{{else}}if err := conjungo.Merge(&merged.{{.Path}}, &src.{{.Path}}, o); err != nil {
+		return fmt.Errorf("failed to merge field ` + "`" + `{{.Struct}}.{{.Label}}` + "`" + `: %w", err)
+	}
+	{{end}}{{if not .Exported}}}
+	{{end}}{{end}}
+	*dst = merged
+	return nil
+}
+
+// Register{{.Name}}MergeFunc sets Merge{{.Name}} as the merge func for {{.Name}} on the options.
+func Register{{.Name}}MergeFunc(o *conjungo.Options) {
+	o.SetTypeMergeFunc(reflect.TypeOf({{.Name}}{}), func(t, s reflect.Value, o *conjungo.Options) (reflect.Value, error) {
+		dst := t.Interface().({{.Name}})
+		if err := Merge{{.Name}}(&dst, s.Interface().({{.Name}}), o); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(dst), nil
+	})
+}
+{{end}}`))
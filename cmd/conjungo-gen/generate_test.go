@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("generate", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "conjungo-gen")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	writeFile := func(name, src string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("is up to date with the example", func() {
+		expected, err := os.ReadFile("internal/example/config_conjungo.go")
+		Expect(err).ToNot(HaveOccurred())
+
+		src, err := generate("internal/example", []string{"Config", "Server"}, "-type Config,Server")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(src)).To(Equal(string(expected)), "run go generate ./cmd/conjungo-gen/internal/example")
+	})
+
+	It("generates code for each kind of field", func() {
+		writeFile("types.go", `package types
+
+type Base struct{ ID int }
+
+type Foo struct {
+	Base
+	embedded
+	Name   string `+"`conjungo:\"name,omitempty\"`"+`
+	A, B   int
+	Tags   []string
+	Labels map[string]bool
+	Other  map[string][]int
+	Bar    Bar
+	Ptr    *Bar
+	hidden string
+	_      int
+}
+
+type Bar struct{ Value string }
+
+type embedded struct {
+	Count  int
+	hidden bool
+}
+`)
+		writeFile("types_test.go", `package types
+
+type Ignored struct{}
+`)
+
+		src, err := generate(dir, []string{"Foo", "Bar"}, "-type Foo,Bar")
+		Expect(err).ToNot(HaveOccurred())
+
+		code := string(src)
+		Expect(code).To(HavePrefix("// Code generated by conjungo-gen -type Foo,Bar; DO NOT EDIT.\n\npackage types\n"))
+		Expect(code).To(ContainSubstring("func MergeFoo(dst *Foo, src Foo, o *conjungo.Options) error {"))
+		Expect(code).To(ContainSubstring("func RegisterFooMergeFunc(o *conjungo.Options) {"))
+		Expect(code).To(ContainSubstring("func MergeBar(dst *Bar, src Bar, o *conjungo.Options) error {"))
+		Expect(code).To(ContainSubstring("conjungo.Merge(&merged.Base, &src.Base, o)"))
+		Expect(code).To(ContainSubstring("merged.A = src.A"))
+		Expect(code).To(ContainSubstring("merged.B = src.B"))
+		Expect(code).To(ContainSubstring("append(merged.Tags, src.Tags...)"))
+		Expect(code).To(ContainSubstring("make(map[string]bool, len(merged.Labels))"))
+		Expect(code).To(ContainSubstring("conjungo.Merge(&merged.Other, &src.Other, o)"))
+		Expect(code).To(ContainSubstring("MergeBar(&merged.Bar, src.Bar, o)"))
+		Expect(code).To(ContainSubstring("reflect.TypeOf(Foo{}.embedded.Count),"))
+		Expect(code).To(ContainSubstring("reflect.TypeOf(Foo{}.Labels),"))
+		Expect(code).To(ContainSubstring("!o.UsesDefaultMergeFuncs(conjungoFooTypes...)"))
+		Expect(code).To(ContainSubstring("!o.UsesDefaultMergeFuncs(reflect.TypeOf(src.Bar))"))
+		Expect(code).To(ContainSubstring("conjungo.Merge(&merged.Ptr, &src.Ptr, o)"))
+		Expect(code).To(ContainSubstring("merged.hidden = src.hidden"))
+		Expect(code).To(ContainSubstring(`"struct of type embedded has unexported field: %s", "hidden"`))
+		Expect(code).ToNot(ContainSubstring("merged._"))
+		Expect(code).To(ContainSubstring("merged.embedded.Count = src.embedded.Count"))
+		Expect(code).To(ContainSubstring("merged.embedded.hidden = src.embedded.hidden"))
+		Expect(code).ToNot(ContainSubstring("&merged.embedded,"))
+	})
+
+	It("omits the fmt import when it is not needed", func() {
+		writeFile("types.go", "package types\n\ntype Foo struct{ Name string }\n")
+
+		src, err := generate(dir, []string{"Foo"}, "-type Foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(src)).ToNot(ContainSubstring(`"fmt"`))
+	})
+
+	It("errors on unknown types", func() {
+		writeFile("types.go", "package types\n\ntype Foo int\n")
+
+		_, err := generate(dir, []string{"Foo"}, "")
+		Expect(err).To(MatchError("struct type Foo not found in " + dir))
+	})
+
+	It("errors on generic types", func() {
+		writeFile("types.go", "package types\n\ntype Foo[T any] struct{ Value T }\n")
+
+		_, err := generate(dir, []string{"Foo"}, "")
+		Expect(err).To(MatchError("generic type Foo is not supported"))
+	})
+
+	It("errors on strategy tags", func() {
+		writeFile("types.go", "package types\n\ntype Foo struct{\n\tCount int `conjungo:\",strategy=sum\"`\n}\n")
+
+		_, err := generate(dir, []string{"Foo"}, "")
+		Expect(err).To(MatchError("field Foo.Count has a merge strategy in its tag, which is not supported"))
+	})
+
+	It("errors on empty directories", func() {
+		_, err := generate(dir, []string{"Foo"}, "")
+		Expect(err).To(MatchError("no Go files found in " + dir))
+	})
+})
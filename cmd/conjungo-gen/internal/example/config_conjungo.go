@@ -0,0 +1,239 @@
+// Code generated by conjungo-gen -type Config,Server; DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/InVisionApp/conjungo"
+)
+
+// conjungoConfigTypes holds the types of the fields MergeConfig merges itself.
+var conjungoConfigTypes = []reflect.Type{
+	reflect.TypeOf(Config{}.Name),
+	reflect.TypeOf(Config{}.Replicas),
+	reflect.TypeOf(Config{}.Ratio),
+	reflect.TypeOf(Config{}.Enabled),
+	reflect.TypeOf(Config{}.Tags),
+	reflect.TypeOf(Config{}.Labels),
+	reflect.TypeOf(Config{}.Weights),
+	reflect.TypeOf(Config{}.Servers),
+}
+
+// MergeConfig merges src onto dst like conjungo.Merge does. If the options set hooks, a
+// policy, limits or merge funcs for the types of its fields, the fields are merged by conjungo
+// instead. If an error occurs, dst is left unmodified.
+func MergeConfig(dst *Config, src Config, o *conjungo.Options) error {
+	if o == nil {
+		o = conjungo.NewOptions()
+	}
+
+	if o.Context != nil {
+		if err := o.Context.Err(); err != nil {
+			return err
+		}
+	}
+
+	if o.RequiresReflection() || !o.UsesDefaultMergeFuncs(conjungoConfigTypes...) {
+		merged, err := conjungo.MergeStruct(reflect.ValueOf(*dst), reflect.ValueOf(src), o)
+		if err != nil {
+			return err
+		}
+		*dst = merged.Interface().(Config)
+		return nil
+	}
+
+	merged := *dst
+
+	if o.Overwrite {
+		merged.Name = src.Name
+	}
+
+	if o.Overwrite {
+		merged.Replicas = src.Replicas
+	}
+
+	if o.Overwrite {
+		merged.Ratio = src.Ratio
+	}
+
+	if o.Overwrite {
+		merged.Enabled = src.Enabled
+	}
+
+	if merged.Tags == nil {
+		merged.Tags = src.Tags
+	} else if src.Tags != nil {
+		merged.Tags = append(merged.Tags, src.Tags...)
+	}
+
+	if merged.Labels == nil {
+		merged.Labels = src.Labels
+	} else if src.Labels != nil {
+		m := make(map[string]string, len(merged.Labels))
+		for k, v := range merged.Labels {
+			m[k] = v
+		}
+		for k, v := range src.Labels {
+			if _, ok := m[k]; !ok || o.Overwrite {
+				m[k] = v
+			}
+		}
+		merged.Labels = m
+	}
+
+	if merged.Weights == nil {
+		merged.Weights = src.Weights
+	} else if src.Weights != nil {
+		m := make(map[string]int, len(merged.Weights))
+		for k, v := range merged.Weights {
+			m[k] = v
+		}
+		for k, v := range src.Weights {
+			if _, ok := m[k]; !ok || o.Overwrite {
+				m[k] = v
+			}
+		}
+		merged.Weights = m
+	}
+
+	if !o.UsesDefaultMergeFuncs(reflect.TypeOf(src.Primary)) {
+		// merged with the func set for the type
+		if err := conjungo.Merge(&merged.Primary, &src.Primary, o); err != nil {
+			return fmt.Errorf("failed to merge field `Config.primary`: %w", err)
+		}
+	} else if err := MergeServer(&merged.Primary, src.Primary, o); err != nil {
+		return fmt.Errorf("failed to merge field `Config.primary`: %w", err)
+	}
+
+	if err := conjungo.Merge(&merged.Backup, &src.Backup, o); err != nil {
+		return fmt.Errorf("failed to merge field `Config.Backup`: %w", err)
+	}
+
+	if err := conjungo.Merge(&merged.Timeout, &src.Timeout, o); err != nil {
+		return fmt.Errorf("failed to merge field `Config.Timeout`: %w", err)
+	}
+
+	if err := conjungo.Merge(&merged.Started, &src.Started, o); err != nil {
+		return fmt.Errorf("failed to merge field `Config.Started`: %w", err)
+	}
+
+	if err := conjungo.Merge(&merged.Extra, &src.Extra, o); err != nil {
+		return fmt.Errorf("failed to merge field `Config.Extra`: %w", err)
+	}
+
+	if merged.Servers == nil {
+		merged.Servers = src.Servers
+	} else if src.Servers != nil {
+		merged.Servers = append(merged.Servers, src.Servers...)
+	}
+
+	if err := conjungo.Merge(&merged.Any, &src.Any, o); err != nil {
+		return fmt.Errorf("failed to merge field `Config.Any`: %w", err)
+	}
+
+	if o.Overwrite {
+		merged.meta.Owner = src.meta.Owner
+	}
+
+	if o.Overwrite {
+		merged.meta.Revision = src.meta.Revision
+	}
+
+	if merged.meta.Notes == nil {
+		merged.meta.Notes = src.meta.Notes
+	} else if src.meta.Notes != nil {
+		merged.meta.Notes = append(merged.meta.Notes, src.meta.Notes...)
+	}
+
+	*dst = merged
+	return nil
+}
+
+// RegisterConfigMergeFunc sets MergeConfig as the merge func for Config on the options.
+func RegisterConfigMergeFunc(o *conjungo.Options) {
+	o.SetTypeMergeFunc(reflect.TypeOf(Config{}), func(t, s reflect.Value, o *conjungo.Options) (reflect.Value, error) {
+		dst := t.Interface().(Config)
+		if err := MergeConfig(&dst, s.Interface().(Config), o); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(dst), nil
+	})
+}
+
+// conjungoServerTypes holds the types of the fields MergeServer merges itself.
+var conjungoServerTypes = []reflect.Type{
+	reflect.TypeOf(Server{}.Host),
+	reflect.TypeOf(Server{}.Port),
+}
+
+// MergeServer merges src onto dst like conjungo.Merge does. If the options set hooks, a
+// policy, limits or merge funcs for the types of its fields, the fields are merged by conjungo
+// instead. If an error occurs, dst is left unmodified.
+func MergeServer(dst *Server, src Server, o *conjungo.Options) error {
+	if o == nil {
+		o = conjungo.NewOptions()
+	}
+
+	if o.Context != nil {
+		if err := o.Context.Err(); err != nil {
+			return err
+		}
+	}
+
+	if o.RequiresReflection() || !o.UsesDefaultMergeFuncs(conjungoServerTypes...) {
+		merged, err := conjungo.MergeStruct(reflect.ValueOf(*dst), reflect.ValueOf(src), o)
+		if err != nil {
+			return err
+		}
+		*dst = merged.Interface().(Server)
+		return nil
+	}
+
+	switch {
+	case o.ErrorOnUnexported:
+		return fmt.Errorf("struct of type Server has unexported field: %s", "secret")
+
+	case !o.MergeUnexported && !o.SkipUnexported:
+		// treat the struct as a single entity
+		if o.Overwrite {
+			*dst = src
+		}
+		return nil
+	}
+
+	merged := *dst
+
+	if o.Overwrite {
+		merged.Host = src.Host
+	}
+
+	if o.Overwrite {
+		merged.Port = src.Port
+	}
+
+	if err := conjungo.Merge(&merged.Ports, &src.Ports, o); err != nil {
+		return fmt.Errorf("failed to merge field `Server.Ports`: %w", err)
+	}
+
+	if o.MergeUnexported {
+		if o.Overwrite {
+			merged.secret = src.secret
+		}
+	}
+
+	*dst = merged
+	return nil
+}
+
+// RegisterServerMergeFunc sets MergeServer as the merge func for Server on the options.
+func RegisterServerMergeFunc(o *conjungo.Options) {
+	o.SetTypeMergeFunc(reflect.TypeOf(Server{}), func(t, s reflect.Value, o *conjungo.Options) (reflect.Value, error) {
+		dst := t.Interface().(Server)
+		if err := MergeServer(&dst, s.Interface().(Server), o); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(dst), nil
+	})
+}
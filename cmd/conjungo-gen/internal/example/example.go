@@ -0,0 +1,43 @@
+// Package example holds types with merge funcs generated by conjungo-gen, which are
+// tested against conjungo.Merge.
+package example
+
+import "time"
+
+//go:generate go run github.com/InVisionApp/conjungo/cmd/conjungo-gen -type Config,Server
+
+// Config is a configuration with fields of each kind handled by conjungo-gen.
+type Config struct {
+	Name     string `conjungo:"name"`
+	Replicas int
+	Ratio    float64
+	Enabled  bool
+	Tags     []string
+	Labels   map[string]string
+	Weights  map[string]int
+	Primary  Server `conjungo:"primary"`
+	Backup   *Server
+	Timeout  time.Duration
+	Started  time.Time
+	Extra    map[string]interface{}
+	Servers  []Server
+	Any      interface{}
+
+	meta
+}
+
+// meta is embedded in Config, whose merge funcs merge its fields like those of Config.
+type meta struct {
+	Owner    string
+	Revision int
+	Notes    []string
+}
+
+// Server is nested in Config.
+type Server struct {
+	Host  string
+	Port  int
+	Ports [2]int
+
+	secret string
+}
@@ -0,0 +1,13 @@
+package example
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestExampleSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Generated Example Suite")
+}
@@ -0,0 +1,201 @@
+package example
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/InVisionApp/conjungo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func newConfigs() (Config, Config) {
+	target := Config{
+		Name:     "target",
+		Replicas: 2,
+		Enabled:  true,
+		Tags:     []string{"a"},
+		Labels:   map[string]string{"a": "1", "b": "2"},
+		Primary:  Server{Host: "primary", Port: 80, Ports: [2]int{1, 0}, secret: "t"},
+		Backup:   &Server{Host: "backup"},
+		Timeout:  time.Second,
+		Extra:    map[string]interface{}{"a": 1, "nested": map[string]interface{}{"x": 1}},
+		Servers:  []Server{{Host: "one"}},
+		Any:      "target",
+		meta:     meta{Owner: "target", Revision: 1, Notes: []string{"a"}},
+	}
+
+	source := Config{
+		Name:    "source",
+		Ratio:   0.5,
+		Tags:    []string{"b"},
+		Labels:  map[string]string{"b": "3", "c": "4"},
+		Weights: map[string]int{"a": 1},
+		Primary: Server{Port: 8080, Ports: [2]int{0, 2}, secret: "s"},
+		Backup:  &Server{Port: 1},
+		Started: time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:   map[string]interface{}{"b": 2, "nested": map[string]interface{}{"y": 2}},
+		Servers: []Server{{Host: "two"}},
+		meta:    meta{Revision: 2, Notes: []string{"b"}},
+	}
+
+	return target, source
+}
+
+func keep(t, s reflect.Value, o *conjungo.Options) (reflect.Value, error) {
+	return t, nil
+}
+
+var _ = Describe("generated merge funcs", func() {
+	DescribeTable("merge like conjungo.Merge",
+		func(configure func(o *conjungo.Options)) {
+			expectedOpts := conjungo.NewOptions()
+			configure(expectedOpts)
+			expected, source := newConfigs()
+			expectedErr := conjungo.Merge(&expected, source, expectedOpts)
+
+			opts := conjungo.NewOptions()
+			configure(opts)
+			target, source := newConfigs()
+			err := MergeConfig(&target, source, opts)
+
+			if expectedErr != nil {
+				Expect(err).To(MatchError(expectedErr.Error()))
+				return
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(expected))
+		},
+		Entry("by default", func(o *conjungo.Options) {}),
+		Entry("without overwrite", func(o *conjungo.Options) { o.Overwrite = false }),
+		Entry("skipping unexported fields", func(o *conjungo.Options) { o.SkipUnexported = true }),
+		Entry("merging unexported fields", func(o *conjungo.Options) { o.MergeUnexported = true }),
+		Entry("erroring on unexported fields", func(o *conjungo.Options) { o.ErrorOnUnexported = true }),
+		Entry("following pointers", func(o *conjungo.Options) {
+			o.PointerPolicy = conjungo.PointerFollow
+			o.SkipUnexported = true
+		}),
+		Entry("limiting slices", func(o *conjungo.Options) {
+			o.MaxSliceLen = 1
+			o.SkipUnexported = true
+		}),
+		Entry("limiting maps", func(o *conjungo.Options) {
+			o.MaxMapKeys = 2
+			o.SkipUnexported = true
+		}),
+		Entry("limiting depth", func(o *conjungo.Options) {
+			// fails at the first field, as map keys are merged in no particular order
+			o.MaxDepth = 1
+			o.SkipUnexported = true
+		}),
+		Entry("within limits", func(o *conjungo.Options) {
+			o.MaxSliceLen = 10
+			o.MaxMapKeys = 10
+			o.SkipUnexported = true
+		}),
+		Entry("with hooks", func(o *conjungo.Options) {
+			o.SkipUnexported = true
+			o.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+				if path == "Tags" {
+					return t, s, conjungo.SkipMerge
+				}
+				return t, s, nil
+			}
+		}),
+		Entry("with a policy", func(o *conjungo.Options) {
+			o.SkipUnexported = true
+			conjungo.WithPolicy(&conjungo.Policy{
+				Rules: []conjungo.PolicyRule{{Path: "Labels", Strategy: conjungo.StrategyKeep}},
+			})(o)
+		}),
+		Entry("layering config", func(o *conjungo.Options) { conjungo.PresetConfigLayering(o) }),
+		Entry("patching like JSON", func(o *conjungo.Options) { conjungo.PresetJSONMergePatch(o) }),
+		Entry("filling in missing values", func(o *conjungo.Options) { conjungo.PresetNoClobber(o) }),
+		Entry("with a kind merge func", func(o *conjungo.Options) {
+			o.SetKindMergeFunc(reflect.Int, conjungo.MergeSum)
+		}),
+		Entry("with a type merge func for a nested type", func(o *conjungo.Options) {
+			o.SetTypeMergeFunc(reflect.TypeOf(Server{}), keep)
+		}),
+		Entry("with a default merge func", func(o *conjungo.Options) { o.SetDefaultMergeFunc(keep) }),
+	)
+
+	It("leaves the target unmodified on error", func() {
+		target, source := newConfigs()
+		opts := conjungo.NewOptions()
+		opts.MaxMapKeys = 2
+
+		err := MergeConfig(&target, source, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to merge field `Config.Labels`"))
+
+		expected, _ := newConfigs()
+		Expect(target).To(Equal(expected))
+	})
+
+	It("honors the context", func() {
+		target, source := newConfigs()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		opts := conjungo.NewOptions()
+		opts.Context = ctx
+
+		err := MergeConfig(&target, source, opts)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("defaults the options", func() {
+		target, source := newConfigs()
+
+		err := MergeConfig(&target, source, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("source"))
+	})
+
+	It("is used by conjungo.Merge once registered", func() {
+		opts := conjungo.NewOptions()
+		opts.SkipUnexported = true
+		RegisterConfigMergeFunc(opts)
+		RegisterServerMergeFunc(opts)
+
+		target, source := newConfigs()
+		expected, _ := newConfigs()
+		Expect(MergeConfig(&expected, source, opts)).To(Succeed())
+
+		targets := map[string]Config{"a": target}
+		err := conjungo.Merge(&targets, map[string]Config{"a": source}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(targets["a"]).To(Equal(expected))
+	})
+})
+
+func BenchmarkMergeConfig(b *testing.B) {
+	opts := conjungo.NewOptions()
+	opts.SkipUnexported = true
+	base, source := newConfigs()
+
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			target := base
+			if err := conjungo.Merge(&target, source, opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			target := base
+			if err := MergeConfig(&target, source, opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+// Command conjungo-gen generates merge functions for struct types which merge them like
+// conjungo.Merge does with the default merge funcs, without the cost of reflection.
+//
+// For each type given, it generates:
+//
+//	func MergeFoo(dst *Foo, src Foo, o *conjungo.Options) error
+//	func RegisterFooMergeFunc(o *conjungo.Options)
+//
+// MergeFoo merges src onto dst field by field. Fields of builtin scalar types, slices,
+// maps of builtin scalar values and other generated types are merged by generated code,
+// following the Overwrite option. Any other field is merged with conjungo.Merge, so that
+// the merge funcs and options set for it apply. Field names in errors are taken from
+// `conjungo` tags. RegisterFooMergeFunc sets MergeFoo as the merge func for Foo on the
+// options, so that it is also used where a Foo is merged as part of a larger value.
+// Fields with a merge strategy in their tag are not supported.
+//
+// Usage:
+//
+//	conjungo-gen -type Foo,Bar [-output file] [dir]
+//
+// It is intended to be run with go generate:
+//
+//	//go:generate conjungo-gen -type Foo,Bar
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma separated list of struct type names; required")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_conjungo.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of conjungo-gen:\n")
+	fmt.Fprintf(os.Stderr, "\tconjungo-gen -type Foo,Bar [-output file] [dir]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	types := strings.Split(*typeNames, ",")
+
+	src, err := generate(dir, types, strings.Join(os.Args[1:], " "))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conjungo-gen: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := *output
+	if out == "" {
+		out = filepath.Join(dir, strings.ToLower(types[0])+"_conjungo.go")
+	}
+
+	if err := os.WriteFile(out, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "conjungo-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
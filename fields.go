@@ -0,0 +1,267 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// tagKey is the struct tag read by conjungo.
+const tagKey = "conjungo"
+
+// UnmatchedFieldsError is returned by MergeFields when ErrorOnUnmatched is set and
+// the source has fields that do not exist on the target.
+type UnmatchedFieldsError struct {
+	// Fields holds the dotted names of the unmatched source fields
+	Fields []string
+}
+
+func (e *UnmatchedFieldsError) Error() string {
+	return fmt.Sprintf("source fields have no match on target: %s", strings.Join(e.Fields, ", "))
+}
+
+// MergeFields merges the given source onto the given target by matching struct fields by
+// name, so the two do not need to be of the same type. This is useful for applying a
+// request struct (e.g. one with pointer fields to signal presence) onto a domain struct.
+// Maps with string keys can also be merged onto structs and structs onto maps, for
+// instance to apply configuration decoded from JSON or YAML onto a typed struct.
+//
+// A source field is matched to the target field of the same name, or to the name given
+// in its `conjungo` tag. A tag of "-" excludes the field. If Options.TagName is set, fields
+// are matched by their names in that tag instead. Nil pointer source fields are
+// skipped, and a pointer source field can be merged onto a field of its element type.
+// Fields of identical types are merged with the same rules as Merge, and nested structs
+// of different types are merged recursively by field name.
+//
+// When merging a map onto a struct, a key matches a field if it equals the field name or
+// its name in a `conjungo`, `json`, `yaml` or `mapstructure` tag, falling back to a case
+// insensitive match of the field name. Nested maps are merged onto nested structs, and
+// scalar values are converted to the field type where that can be done without loss.
+// When merging a struct onto a map, fields are written under their name according to
+// Options.TagName and nested structs are written as nested maps if the map holds interface
+// values. Fields tagged `omitempty` are left out if they hold zero values, and the fields
+// of struct fields tagged `inline` are matched and written at the level of the parent.
+//
+// Source fields and keys that have no match on the target are ignored unless
+// ErrorOnUnmatched is set.
+func MergeFields(target, source interface{}, opt *Options) error {
+	return mergeRoot(target, source, opt, mergeFields)
+}
+
+func mergeFields(valT, valS reflect.Value, opt *Options) (reflect.Value, error) {
+	var unmatched []string
+
+	merged, err := mergeFieldsAt(valT, valS, opt, "", &unmatched)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if len(unmatched) > 0 {
+		if opt.ErrorOnUnmatched {
+			return reflect.Value{}, &UnmatchedFieldsError{Fields: unmatched}
+		}
+
+		opt.logger().Debugf("ignoring unmatched source fields: %s", strings.Join(unmatched, ", "))
+	}
+
+	return merged, nil
+}
+
+// mergeFieldsAt merges two values that may be of different types. Values of identical
+// types are handed to merge(). The prefix is the dotted path of the value, used to
+// report unmatched fields and as the path of the values handed to merge().
+func mergeFieldsAt(valT, valS reflect.Value, opt *Options, prefix string, unmatched *[]string) (reflect.Value, error) {
+	if isEmpty(valS) {
+		return valT, nil
+	}
+
+	if valT.IsValid() && valT.Type() == valS.Type() {
+		return mergeAt(valT, valS, opt, prefix)
+	}
+
+	// unwrap interfaces to get to the real types
+	if valS.Kind() == reflect.Interface {
+		valS = valS.Elem()
+	}
+	if valT.IsValid() && valT.Kind() == reflect.Interface && !valT.IsNil() {
+		valT = valT.Elem()
+	}
+
+	if valS.Kind() == reflect.Ptr {
+		return mergeFieldsAt(valT, valS.Elem(), opt, prefix, unmatched)
+	}
+
+	if !valT.IsValid() {
+		return reflect.Value{}, fmt.Errorf("can not merge %v into an invalid value", valS.Type())
+	}
+
+	if valT.Type() == valS.Type() {
+		return mergeAt(valT, valS, opt, prefix)
+	}
+
+	// a nil interface target can hold the source as is
+	if isEmpty(valT) && valT.Kind() == reflect.Interface && valS.Type().AssignableTo(valT.Type()) {
+		return valS, nil
+	}
+
+	switch {
+	case valT.Kind() == reflect.Ptr:
+		// merge onto the pointee and point to the result
+		var merged reflect.Value
+		var err error
+		if valT.IsNil() && valT.Type().Elem() == valS.Type() {
+			merged = valS
+		} else {
+			elemT := reflect.New(valT.Type().Elem()).Elem()
+			if !valT.IsNil() {
+				elemT = valT.Elem()
+			}
+
+			if merged, err = mergeFieldsAt(elemT, valS, opt, prefix, unmatched); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+
+		ptr := reflect.New(valT.Type().Elem())
+		ptr.Elem().Set(merged)
+		return ptr, nil
+
+	case valT.Kind() == reflect.Struct && valS.Kind() == reflect.Struct:
+		return mergeStructFields(valT, valS, opt, prefix, unmatched)
+
+	case valT.Kind() == reflect.Struct && valS.Kind() == reflect.Map:
+		return mergeMapIntoStruct(valT, valS, opt, prefix, unmatched)
+
+	case valT.Kind() == reflect.Map && valS.Kind() == reflect.Struct:
+		return mergeStructIntoMap(valT, valS, opt, prefix, unmatched)
+
+	case valT.Kind() == reflect.Slice && valS.Kind() == reflect.Slice:
+		converted, err := convertSlice(valT.Type(), valS, opt, prefix, unmatched)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return mergeAt(valT, converted, opt, prefix)
+
+	case valT.Kind() == reflect.Map && valS.Kind() == reflect.Map:
+		converted, err := convertMap(valT.Type(), valS, opt, prefix, unmatched)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return mergeAt(valT, converted, opt, prefix)
+	}
+
+	if converted, ok, err := convertValue(valS, valT.Type()); err != nil {
+		return reflect.Value{}, err
+	} else if ok {
+		return mergeAt(valT, converted, opt, prefix)
+	}
+
+	return reflect.Value{}, fmt.Errorf("can not merge %v into %v", valS.Type(), valT.Type())
+}
+
+// mergeStructFields merges each exported field of the source struct onto the target field
+// of the same name. The result is a copy of the target so that target fields which are not
+// in the source are preserved.
+func mergeStructFields(valT, valS reflect.Value, opt *Options, prefix string, unmatched *[]string) (reflect.Value, error) {
+	newT := reflect.New(valT.Type()).Elem()
+	newT.Set(valT)
+
+	fieldsT := map[string][]int{}
+	for _, f := range cachedStructFields(valT.Type(), opt) {
+		fieldsT[f.name] = f.index
+	}
+
+	for _, fieldS := range cachedStructFields(valS.Type(), opt) {
+		name := fieldS.name
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		index, ok := fieldsT[name]
+		if !ok {
+			// fall back to the Go name, which also finds fields promoted from embedded structs
+			fieldT, found := newT.Type().FieldByName(name)
+			if !found || fieldT.PkgPath != "" {
+				*unmatched = append(*unmatched, path)
+				continue
+			}
+			index = fieldT.Index
+		}
+
+		valFieldS, err := valS.FieldByIndexErr(fieldS.index)
+		if err != nil {
+			// promoted through a nil embedded pointer
+			continue
+		}
+
+		valFieldT, ok := fieldByIndexAlloc(newT, index)
+		if !ok {
+			// promoted through an unexported embedded pointer
+			*unmatched = append(*unmatched, path)
+			continue
+		}
+
+		merged, err := mergeFieldsAt(valFieldT, valFieldS, opt, path, unmatched)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to merge field `%s.%s`: %w",
+				newT.Type().Name(), name, err)
+		}
+
+		if !merged.IsValid() {
+			continue
+		}
+
+		if valFieldT.Kind() != reflect.Interface && valFieldT.Type() != merged.Type() {
+			return reflect.Value{}, fmt.Errorf("types dont match %v <> %v", valFieldT.Type(), merged.Type())
+		}
+
+		valFieldT.Set(merged)
+	}
+
+	return newT, nil
+}
+
+// parseTag splits a struct tag value into its name and the comma separated options that follow.
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, tagOptions("")
+}
+
+// tagOptions is the portion of a struct tag value following the name.
+type tagOptions string
+
+// Contains reports whether the comma separated options include the given one.
+func (o tagOptions) Contains(name string) bool {
+	s := string(o)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == name {
+			return true
+		}
+		s = next
+	}
+	return false
+}
+
+// Value returns the value of an option of the form key=value.
+func (o tagOptions) Value(key string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if strings.HasPrefix(s, key+"=") {
+			return s[len(key)+1:], true
+		}
+		s = next
+	}
+	return "", false
+}
@@ -0,0 +1,193 @@
+package conjungo
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeFields", func() {
+	type Address struct {
+		Street string
+		Zip    string
+	}
+
+	type User struct {
+		ID      int
+		Name    string
+		Email   string
+		Tags    []string
+		Address Address
+		Manager *Address
+	}
+
+	type AddressUpdate struct {
+		Zip *string
+	}
+
+	type UpdateUserRequest struct {
+		Name    *string
+		Mail    *string `conjungo:"Email"`
+		Tags    []string
+		Address *AddressUpdate
+		Manager *AddressUpdate
+		Ignored string `conjungo:"-"`
+	}
+
+	var (
+		target User
+		opts   *Options
+	)
+
+	strPtr := func(s string) *string { return &s }
+
+	BeforeEach(func() {
+		target = User{
+			ID:      7,
+			Name:    "target",
+			Email:   "target@example.com",
+			Tags:    []string{"a"},
+			Address: Address{Street: "Main St", Zip: "00000"},
+		}
+		opts = NewOptions()
+	})
+
+	Context("happy path", func() {
+		It("merges matching fields and leaves the rest", func() {
+			source := UpdateUserRequest{
+				Name:    strPtr("source"),
+				Tags:    []string{"b"},
+				Address: &AddressUpdate{Zip: strPtr("12345")},
+				Ignored: "ignored",
+			}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(target.ID).To(Equal(7))
+			Expect(target.Name).To(Equal("source"))
+			Expect(target.Email).To(Equal("target@example.com"))
+			Expect(target.Tags).To(Equal([]string{"a", "b"}))
+			Expect(target.Address).To(Equal(Address{Street: "Main St", Zip: "12345"}))
+			Expect(target.Manager).To(BeNil())
+		})
+
+		It("maps fields using the tag", func() {
+			source := UpdateUserRequest{Mail: strPtr("source@example.com")}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Email).To(Equal("source@example.com"))
+		})
+
+		It("allocates nested pointer structs", func() {
+			source := UpdateUserRequest{Manager: &AddressUpdate{Zip: strPtr("99999")}}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Manager).ToNot(BeNil())
+			Expect(target.Manager.Zip).To(Equal("99999"))
+		})
+
+		It("honors overwrite", func() {
+			opts.Overwrite = false
+			source := UpdateUserRequest{Name: strPtr("source")}
+
+			err := MergeFields(&target, &source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Name).To(Equal("target"))
+		})
+
+		It("merges identical types like Merge", func() {
+			source := User{Name: "source", Tags: []string{"b"}}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Name).To(Equal("source"))
+			Expect(target.Tags).To(Equal([]string{"a", "b"}))
+		})
+	})
+
+	Context("unmatched fields", func() {
+		type Extra struct {
+			Name    *string
+			Unknown string
+			Address struct {
+				Country string
+			}
+		}
+
+		var source Extra
+
+		BeforeEach(func() {
+			source = Extra{Name: strPtr("source"), Unknown: "x"}
+			source.Address.Country = "NZ"
+		})
+
+		It("ignores them by default", func() {
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Name).To(Equal("source"))
+		})
+
+		It("reports them with ErrorOnUnmatched", func() {
+			opts.ErrorOnUnmatched = true
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).To(HaveOccurred())
+
+			unmatchedErr, ok := err.(*UnmatchedFieldsError)
+			Expect(ok).To(BeTrue())
+			Expect(unmatchedErr.Fields).To(Equal([]string{"Unknown", "Address.Country"}))
+			Expect(target.Name).To(Equal("target"))
+		})
+	})
+
+	Context("failure modes", func() {
+		It("errors on incompatible field types", func() {
+			type Bad struct {
+				Name int
+			}
+
+			err := MergeFields(&target, Bad{Name: 1}, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to merge field `User.Name`: can not merge int into string"))
+			Expect(target.Name).To(Equal("target"))
+		})
+
+		It("names each nested field once in errors", func() {
+			type BadAddress struct {
+				Zip int
+			}
+			type Bad struct {
+				Address BadAddress
+			}
+
+			err := MergeFields(&target, Bad{Address: BadAddress{Zip: 1}}, opts)
+			Expect(err).To(MatchError("failed to merge field `User.Address`: " +
+				"failed to merge field `Address.Zip`: can not merge int into string"))
+		})
+
+		It("requires a pointer target", func() {
+			err := MergeFields(target, UpdateUserRequest{}, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("target must be a pointer"))
+		})
+	})
+})
+
+var _ = Describe("tagOptions", func() {
+	It("parses the name and options", func() {
+		name, opts := parseTag("foo,omitempty,inline")
+		Expect(name).To(Equal("foo"))
+		Expect(opts.Contains("omitempty")).To(BeTrue())
+		Expect(opts.Contains("inline")).To(BeTrue())
+		Expect(opts.Contains("foo")).To(BeFalse())
+	})
+
+	It("handles a bare name", func() {
+		name, opts := parseTag("foo")
+		Expect(name).To(Equal("foo"))
+		Expect(opts.Contains("")).To(BeFalse())
+		Expect(string(opts)).To(BeEmpty())
+	})
+})
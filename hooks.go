@@ -0,0 +1,73 @@
+package conjungo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// SkipMerge can be returned by a BeforeMergeFunc to skip merging a value, along with
+// everything below it. The target returned alongside it is used as the result as is.
+var SkipMerge = errors.New("skip merge")
+
+// A BeforeMergeFunc is called before each value is merged, with the path of the value and
+// the target and source about to be merged. The target is invalid if there is no target
+// value, for example for a key that is only in the source map.
+// It returns the target and source to merge in their place, so it can substitute either.
+// Returning SkipMerge skips the merge and uses the returned target as the result, and any
+// other error aborts the merge.
+type BeforeMergeFunc func(path string, target, source reflect.Value) (reflect.Value, reflect.Value, error)
+
+// An AfterMergeFunc is called after each value is merged, with the path of the value, the
+// target and source that were merged, and the result and error of the merge. The result
+// and error it returns are used in their place.
+type AfterMergeFunc func(path string, target, source, result reflect.Value, err error) (reflect.Value, error)
+
+// Path returns the path of the value currently being merged, for use by merge funcs.
+// Struct fields and map keys are separated by dots, and array indexes are given in
+// brackets, for example `Servers.primary.Ports[0]`. The root value has an empty path.
+// Struct fields are named according to Options.TagName.
+func (o *Options) Path() string {
+	if o.state == nil {
+		return ""
+	}
+
+	return o.state.path
+}
+
+// mergeAt merges two values at the given path.
+func mergeAt(valT, valS reflect.Value, opt *Options, path string) (reflect.Value, error) {
+	return mergeAtWith(valT, valS, opt, path, nil)
+}
+
+// mergeAtWith merges two values at the given path with the given merge func, or the merge
+// func for their type if it is nil.
+func mergeAtWith(valT, valS reflect.Value, opt *Options, path string, mf MergeFunc) (reflect.Value, error) {
+	opt = opt.withState()
+
+	parent := opt.state.path
+	opt.state.path = path
+	defer func() { opt.state.path = parent }()
+
+	return mergeWith(valT, valS, opt, mf)
+}
+
+// mergeChild merges two values which are children of the values currently being merged.
+// The segment names the children within their parents: a field name, map key or an index
+// in brackets.
+func mergeChild(valT, valS reflect.Value, opt *Options, segment string) (reflect.Value, error) {
+	return mergeAtWith(valT, valS, opt, joinPath(opt.Path(), segment), nil)
+}
+
+// mergeChildWith is like mergeChild, but merges the children with the given merge func.
+func mergeChildWith(valT, valS reflect.Value, opt *Options, segment string, mf MergeFunc) (reflect.Value, error) {
+	return mergeAtWith(valT, valS, opt, joinPath(opt.Path(), segment), mf)
+}
+
+func joinPath(parent, segment string) string {
+	if parent == "" || strings.HasPrefix(segment, "[") {
+		return parent + segment
+	}
+
+	return parent + "." + segment
+}
@@ -0,0 +1,179 @@
+package conjungo
+
+import (
+	"errors"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("merge hooks", func() {
+	type Server struct {
+		Host  string
+		Ports [2]int
+	}
+
+	type Config struct {
+		Name    string `json:"name"`
+		Secret  string `json:"secret"`
+		Servers map[string]Server
+	}
+
+	var (
+		target, source Config
+		opts           *Options
+	)
+
+	BeforeEach(func() {
+		target = Config{
+			Name:    "target",
+			Secret:  "old",
+			Servers: map[string]Server{"primary": {Host: "a", Ports: [2]int{1, 2}}},
+		}
+		source = Config{
+			Name:    "source",
+			Secret:  "new",
+			Servers: map[string]Server{"primary": {Host: "b", Ports: [2]int{3, 0}}},
+		}
+		opts = NewOptions()
+	})
+
+	It("calls BeforeMerge with the path of each value", func() {
+		var paths []string
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			paths = append(paths, path)
+			return t, s, nil
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).To(Equal([]string{
+			"", "Name", "Secret", "Servers", "Servers.primary",
+			"Servers.primary.Host", "Servers.primary.Ports", "Servers.primary.Ports[0]",
+		}))
+	})
+
+	It("names fields according to TagName", func() {
+		var paths []string
+		opts.TagName = "json"
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			paths = append(paths, path)
+			return t, s, nil
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).To(ContainElement("name"))
+		Expect(paths).To(ContainElement("secret"))
+	})
+
+	It("skips a subtree", func() {
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			if path == "Servers" {
+				return t, s, SkipMerge
+			}
+			return t, s, nil
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("source"))
+		Expect(target.Servers["primary"].Host).To(Equal("a"))
+	})
+
+	It("substitutes values", func() {
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			if path == "Secret" {
+				return t, reflect.ValueOf("[redacted]"), nil
+			}
+			return t, s, nil
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Secret).To(Equal("[redacted]"))
+	})
+
+	It("aborts the merge on an error from BeforeMerge", func() {
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			if path == "Servers.primary.Host" {
+				return t, s, errors.New("denied")
+			}
+			return t, s, nil
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("denied"))
+		Expect(target.Name).To(Equal("target"))
+	})
+
+	It("calls AfterMerge with the result of each value", func() {
+		results := map[string]interface{}{}
+		opts.AfterMerge = func(path string, t, s, result reflect.Value, err error) (reflect.Value, error) {
+			Expect(err).ToNot(HaveOccurred())
+			results[path] = result.Interface()
+			return result, err
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results["Name"]).To(Equal("source"))
+		Expect(results["Servers.primary.Ports"]).To(Equal([2]int{3, 2}))
+		Expect(results[""]).To(Equal(target))
+	})
+
+	It("lets AfterMerge replace results and errors", func() {
+		opts.SetTypeMergeFunc(reflect.TypeOf(""), erroringMergeFunc)
+		opts.AfterMerge = func(path string, t, s, result reflect.Value, err error) (reflect.Value, error) {
+			if path == "Secret" {
+				return t, nil
+			}
+			if err != nil {
+				return s, nil
+			}
+			return result, nil
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("source"))
+		Expect(target.Secret).To(Equal("old"))
+	})
+
+	It("exposes the path to merge funcs", func() {
+		var paths []string
+		opts.SetTypeMergeFunc(reflect.TypeOf(""), func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+			paths = append(paths, o.Path())
+			return s, nil
+		})
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).To(Equal([]string{"Name", "Secret", "Servers.primary.Host"}))
+		Expect(opts.Path()).To(BeEmpty())
+	})
+
+	It("uses the paths of MergeFields", func() {
+		var paths []string
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			paths = append(paths, path)
+			return t, s, nil
+		}
+
+		err := MergeFields(&target, map[string]interface{}{"name": "fields"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("fields"))
+		Expect(paths).To(Equal([]string{"name"}))
+	})
+})
+
+var _ = Describe("joinPath", func() {
+	It("joins segments", func() {
+		Expect(joinPath("", "a")).To(Equal("a"))
+		Expect(joinPath("a", "b")).To(Equal("a.b"))
+		Expect(joinPath("a", "[0]")).To(Equal("a[0]"))
+		Expect(joinPath("", "[0]")).To(Equal("[0]"))
+	})
+})
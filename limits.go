@@ -0,0 +1,181 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// LimitError is returned when a merge exceeds one of the limits set on Options.
+type LimitError struct {
+	// Limit is the name of the Options field holding the limit that was exceeded
+	Limit string
+
+	// Max is the value of the limit
+	Max int
+
+	// Value is the depth, length or count that exceeded it
+	Value int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("merge limit exceeded: %s is %d but got %d", e.Limit, e.Max, e.Value)
+}
+
+// enter records that merge() is visiting another node one level deeper, and checks the
+// MaxNodes and MaxDepth limits. The returned func must be called when leaving the node.
+func (st *mergeState) enter(opt *Options) (func(), error) {
+	st.nodes++
+	if opt.MaxNodes > 0 && st.nodes > opt.MaxNodes {
+		return nil, &LimitError{Limit: "MaxNodes", Max: opt.MaxNodes, Value: st.nodes}
+	}
+
+	st.depth++
+	leave := func() { st.depth-- }
+	if opt.MaxDepth > 0 && st.depth > opt.MaxDepth {
+		err := &LimitError{Limit: "MaxDepth", Max: opt.MaxDepth, Value: st.depth}
+		leave()
+		return nil, err
+	}
+
+	return leave, nil
+}
+
+// checkSize checks the MaxSliceLen and MaxMapKeys limits against a slice or map of the
+// given kind which is about to hold n elements.
+func checkSize(kind reflect.Kind, n int, opt *Options) error {
+	switch {
+	case kind == reflect.Slice && opt.MaxSliceLen > 0 && n > opt.MaxSliceLen:
+		return &LimitError{Limit: "MaxSliceLen", Max: opt.MaxSliceLen, Value: n}
+
+	case kind == reflect.Map && opt.MaxMapKeys > 0 && n > opt.MaxMapKeys:
+		return &LimitError{Limit: "MaxMapKeys", Max: opt.MaxMapKeys, Value: n}
+	}
+
+	return nil
+}
+
+// checkSizes checks the limits against a value taken as is from the source, such as one
+// under a key that is not in the target, and against the values it holds, including those
+// held in interfaces. The MaxSliceLen and MaxMapKeys limits are checked against its slices
+// and maps, and the MaxDepth and MaxNodes limits against their elements and the fields of
+// its structs, as though each had been merged. The value itself is counted as the node
+// being merged.
+func checkSizes(v reflect.Value, opt *Options) error {
+	return walkLimits(v, opt, false)
+}
+
+// checkChildSizes checks the limits like checkSizes against a value taken as is from the
+// source in place of a child of the node being merged, counting it as a node of its own.
+func checkChildSizes(v reflect.Value, opt *Options) error {
+	return walkLimits(v, opt, true)
+}
+
+// walkLimits walks the values held by v, depth first, with a stack rather than recursion so
+// that deeply nested values can not exhaust the call stack.
+func walkLimits(v reflect.Value, opt *Options, child bool) error {
+	if opt.MaxSliceLen <= 0 && opt.MaxMapKeys <= 0 && opt.MaxDepth <= 0 && opt.MaxNodes <= 0 {
+		return nil
+	}
+
+	// nodes are counted towards the merge in progress, if any
+	st := opt.state
+	if st == nil {
+		st = newMergeState()
+	}
+
+	type node struct {
+		v     reflect.Value
+		depth int
+	}
+	var stack []node
+
+	push := func(v reflect.Value, depth int) error {
+		st.nodes++
+		if opt.MaxNodes > 0 && st.nodes > opt.MaxNodes {
+			return &LimitError{Limit: "MaxNodes", Max: opt.MaxNodes, Value: st.nodes}
+		}
+		if opt.MaxDepth > 0 && depth > opt.MaxDepth {
+			return &LimitError{Limit: "MaxDepth", Max: opt.MaxDepth, Value: depth}
+		}
+
+		stack = append(stack, node{v: v, depth: depth})
+		return nil
+	}
+
+	if child {
+		if err := push(v, st.depth+1); err != nil {
+			return err
+		}
+	} else {
+		stack = append(stack, node{v: v, depth: st.depth})
+	}
+
+	seen := map[visitKey]bool{}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		v := n.v
+
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			if v.IsNil() {
+				continue
+			}
+		}
+
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			// values may hold themselves
+			key := visitKey{target: v.Pointer(), typ: v.Type()}
+			if v.Kind() == reflect.Slice {
+				key.source = uintptr(v.Len())
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr:
+			stack = append(stack, node{v: v.Elem(), depth: n.depth})
+
+		case reflect.Map:
+			if err := checkSize(reflect.Map, v.Len(), opt); err != nil {
+				return err
+			}
+
+			iter := v.MapRange()
+			for iter.Next() {
+				if err := push(iter.Value(), n.depth+1); err != nil {
+					return err
+				}
+			}
+
+		case reflect.Slice, reflect.Array:
+			if v.Kind() == reflect.Slice {
+				if err := checkSize(reflect.Slice, v.Len(), opt); err != nil {
+					return err
+				}
+			}
+
+			for i := 0; i < v.Len(); i++ {
+				if err := push(v.Index(i), n.depth+1); err != nil {
+					return err
+				}
+			}
+
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).PkgPath != "" {
+					continue
+				}
+				if err := push(v.Field(i), n.depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
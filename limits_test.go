@@ -0,0 +1,180 @@
+package conjungo
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("limits", func() {
+	var opts *Options
+
+	BeforeEach(func() {
+		opts = NewOptions()
+	})
+
+	expectLimit := func(err error, limit string, max int) {
+		Expect(err).To(HaveOccurred())
+
+		var limitErr *LimitError
+		Expect(errors.As(err, &limitErr)).To(BeTrue())
+		Expect(limitErr.Limit).To(Equal(limit))
+		Expect(limitErr.Max).To(Equal(max))
+	}
+
+	nested := func(depth int) map[string]interface{} {
+		m := map[string]interface{}{"leaf": true}
+		for i := 0; i < depth; i++ {
+			m = map[string]interface{}{"child": m}
+		}
+		return m
+	}
+
+	Context("MaxDepth", func() {
+		It("errors when values are nested too deeply", func() {
+			opts.MaxDepth = 3
+			target := nested(5)
+
+			err := Merge(&target, nested(5), opts)
+			expectLimit(err, "MaxDepth", 3)
+			Expect(target).To(Equal(nested(5)))
+		})
+
+		It("merges values within the limit", func() {
+			opts.MaxDepth = 10
+			target := nested(5)
+
+			err := Merge(&target, nested(5), opts)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("errors when a new key holds values nested too deeply", func() {
+			opts.MaxDepth = 3
+			target := map[string]interface{}{"a": 1}
+
+			err := Merge(&target, map[string]interface{}{"b": nested(50)}, opts)
+			expectLimit(err, "MaxDepth", 3)
+			Expect(target).To(Equal(map[string]interface{}{"a": 1}))
+		})
+
+		It("takes new keys holding values within the limit", func() {
+			opts.MaxDepth = 8
+			target := map[string]interface{}{"a": 1}
+
+			err := Merge(&target, map[string]interface{}{"b": nested(5)}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target["b"]).To(Equal(nested(5)))
+		})
+	})
+
+	Context("MaxNodes", func() {
+		It("errors when too many values are visited", func() {
+			opts.MaxNodes = 5
+			target := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+			source := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+
+			err := Merge(&target, source, opts)
+			expectLimit(err, "MaxNodes", 5)
+		})
+
+		It("counts the values held by a new key", func() {
+			opts.MaxNodes = 5
+			target := map[string]interface{}{"a": 1}
+
+			err := Merge(&target, map[string]interface{}{"b": nested(50)}, opts)
+			expectLimit(err, "MaxNodes", 5)
+		})
+
+		It("counts nodes across the whole merge", func() {
+			opts.MaxNodes = 6
+			target := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+			source := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("MaxSliceLen", func() {
+		It("errors when an appended slice is too long", func() {
+			opts.MaxSliceLen = 4
+			target := []int{1, 2, 3}
+
+			err := Merge(&target, []int{4, 5}, opts)
+			expectLimit(err, "MaxSliceLen", 4)
+			Expect(target).To(Equal([]int{1, 2, 3}))
+		})
+
+		It("errors when a source slice is too long for an empty target", func() {
+			opts.MaxSliceLen = 1
+			target := map[string][]int{}
+
+			err := Merge(&target, map[string][]int{"a": {1, 2}}, opts)
+			expectLimit(err, "MaxSliceLen", 1)
+		})
+
+		It("errors when a new key holds a slice in an interface", func() {
+			opts.MaxSliceLen = 3
+			target := map[string]interface{}{}
+
+			err := Merge(&target, map[string]interface{}{"a": []interface{}{1, 2, 3, 4, 5}}, opts)
+			expectLimit(err, "MaxSliceLen", 3)
+			Expect(target).To(BeEmpty())
+		})
+
+		It("errors when an appended element holds a slice that is too long", func() {
+			opts.MaxSliceLen = 3
+			target := []interface{}{1}
+
+			err := Merge(&target, []interface{}{map[string]interface{}{"b": []interface{}{1, 2, 3, 4}}}, opts)
+			expectLimit(err, "MaxSliceLen", 3)
+		})
+
+		It("appends within the limit", func() {
+			opts.MaxSliceLen = 5
+			target := []int{1, 2, 3}
+
+			err := Merge(&target, []int{4, 5}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal([]int{1, 2, 3, 4, 5}))
+		})
+	})
+
+	Context("MaxMapKeys", func() {
+		It("errors when a merged map has too many keys", func() {
+			opts.MaxMapKeys = 2
+			target := map[string]int{"a": 1, "b": 2}
+
+			err := Merge(&target, map[string]int{"c": 3}, opts)
+			expectLimit(err, "MaxMapKeys", 2)
+			Expect(target).To(Equal(map[string]int{"a": 1, "b": 2}))
+		})
+
+		It("errors when a new key holds a map in an interface", func() {
+			opts.MaxMapKeys = 2
+			target := map[string]interface{}{}
+			source := map[string]interface{}{
+				"a": map[string]interface{}{"nested": map[string]interface{}{"x": 1, "y": 2, "z": 3}},
+			}
+
+			err := Merge(&target, source, opts)
+			expectLimit(err, "MaxMapKeys", 2)
+			Expect(target).To(BeEmpty())
+		})
+
+		It("does not count keys already in the target", func() {
+			opts.MaxMapKeys = 2
+			target := map[string]int{"a": 1, "b": 2}
+
+			err := Merge(&target, map[string]int{"a": 3}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(map[string]int{"a": 3, "b": 2}))
+		})
+	})
+
+	It("reports the limit in the error message", func() {
+		err := (&LimitError{Limit: "MaxDepth", Max: 2, Value: 3}).Error()
+		Expect(err).To(Equal("merge limit exceeded: MaxDepth is 2 but got 3"))
+	})
+})
@@ -0,0 +1,83 @@
+package conjungo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+)
+
+// Logger receives the log messages emitted during a merge. Debug messages describe the
+// values being merged, warnings report fallbacks to default behavior, and trace messages
+// report each value entered, the merge func selected for it and its result.
+// Use Options.Logger to set one. Loggers such as *logrus.Logger implement it as is, and
+// NewSlogLogger adapts a *slog.Logger.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Tracef(format string, args ...interface{})
+}
+
+// LevelTrace is the slog level at which the logger returned by NewSlogLogger logs trace messages.
+const LevelTrace = slog.LevelDebug - 4
+
+// NewSlogLogger returns a Logger that logs to the given *slog.Logger. Trace messages are
+// logged at LevelTrace.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.logf(slog.LevelDebug, format, args)
+}
+
+func (s *slogLogger) Warnf(format string, args ...interface{}) {
+	s.logf(slog.LevelWarn, format, args)
+}
+
+func (s *slogLogger) Tracef(format string, args ...interface{}) {
+	s.logf(LevelTrace, format, args)
+}
+
+func (s *slogLogger) logf(level slog.Level, format string, args []interface{}) {
+	ctx := context.Background()
+
+	// skip formatting the message if it would be discarded
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+
+	s.l.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+// nopLogger discards all messages. It is used when Options.Logger is not set.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Tracef(string, ...interface{}) {}
+
+// logger returns the logger set on the options, or one that discards all messages.
+// Where messages are emitted for every value merged, check Options.Logger instead to
+// avoid the cost of building the arguments.
+func (o *Options) logger() Logger {
+	if o.Logger == nil {
+		return nopLogger{}
+	}
+
+	return o.Logger
+}
+
+// funcName returns the name of the given merge func, for tracing.
+func funcName(f MergeFunc) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return fn.Name()
+	}
+
+	return "unknown"
+}
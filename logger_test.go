@@ -0,0 +1,109 @@
+package conjungo
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingLogger struct {
+	debug, warn, trace []string
+}
+
+func (r *recordingLogger) Debugf(format string, args ...interface{}) {
+	r.debug = append(r.debug, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingLogger) Warnf(format string, args ...interface{}) {
+	r.warn = append(r.warn, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingLogger) Tracef(format string, args ...interface{}) {
+	r.trace = append(r.trace, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("Logger", func() {
+	var opts *Options
+
+	BeforeEach(func() {
+		opts = NewOptions()
+	})
+
+	It("discards messages by default", func() {
+		Expect(opts.logger()).To(Equal(nopLogger{}))
+
+		target := map[string]int{"a": 1}
+		err := Merge(&target, map[string]int{"a": 2}, opts)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("emits debug and trace messages to the configured logger", func() {
+		logger := &recordingLogger{}
+		opts.Logger = logger
+
+		target := map[string]int{"a": 1}
+		err := Merge(&target, map[string]int{"a": 2}, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(logger.debug).To(ConsistOf("MERGE T<>S 'a' :: 1 <> 2"))
+		Expect(logger.trace).To(Equal([]string{
+			"enter map[string]int at depth 1: selected github.com/InVisionApp/conjungo.mergeMap",
+			"enter int at depth 2: selected github.com/InVisionApp/conjungo.defaultMergeFunc",
+			"leave int at depth 2: result: 2",
+			"leave map[string]int at depth 1: result: map[a:2]",
+		}))
+	})
+
+	It("traces errors", func() {
+		logger := &recordingLogger{}
+		opts.Logger = logger
+		opts.SetKindMergeFunc(reflect.Int, erroringMergeFunc)
+
+		target := map[string]int{"a": 1}
+		err := Merge(&target, map[string]int{"a": 2}, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(logger.trace).To(ContainElement("leave int at depth 2: error: returns error"))
+	})
+
+	Context("slog adapter", func() {
+		var buf *bytes.Buffer
+
+		newLogger := func(level slog.Level) Logger {
+			buf = &bytes.Buffer{}
+			handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+				Level: level,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})
+			return NewSlogLogger(slog.New(handler))
+		}
+
+		It("logs at the matching levels", func() {
+			logger := newLogger(LevelTrace)
+			logger.Debugf("debug %d", 1)
+			logger.Warnf("warn %d", 2)
+			logger.Tracef("trace %d", 3)
+
+			Expect(buf.String()).To(Equal("level=DEBUG msg=\"debug 1\"\n" +
+				"level=WARN msg=\"warn 2\"\n" +
+				"level=DEBUG-4 msg=\"trace 3\"\n"))
+		})
+
+		It("respects the handler level", func() {
+			logger := newLogger(slog.LevelWarn)
+			logger.Debugf("debug")
+			logger.Tracef("trace")
+			logger.Warnf("warn")
+
+			Expect(buf.String()).To(Equal("level=WARN msg=warn\n"))
+		})
+	})
+})
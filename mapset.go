@@ -0,0 +1,153 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// The map merge funcs below treat maps as sets of their keys, such as map[T]struct{} or
+// map[T]bool. A key is in the set if it is in the map, unless its value is a bool which is
+// false. Where a key is kept from both maps, its values are merged.
+
+// MergeMapUnion is a MergeFunc for maps which keeps the keys in either map.
+func MergeMapUnion(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeMapSet(t, s, o, StrategyUnion, func(inT, inS bool) bool {
+		return inT || inS
+	})
+}
+
+// MergeMapIntersect is a MergeFunc for maps which keeps the keys in both maps.
+func MergeMapIntersect(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeMapSet(t, s, o, StrategyIntersect, func(inT, inS bool) bool {
+		return inT && inS
+	})
+}
+
+// MergeMapSubtract is a MergeFunc for maps which keeps the keys of the target that are not
+// in the source.
+func MergeMapSubtract(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeMapSet(t, s, o, StrategySubtract, func(inT, inS bool) bool {
+		return inT && !inS
+	})
+}
+
+// MergeMapSymmetricDifference is a MergeFunc for maps which keeps the keys that are in
+// either map but not in both.
+func MergeMapSymmetricDifference(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeMapSet(t, s, o, StrategySymmetricDifference, func(inT, inS bool) bool {
+		return inT != inS
+	})
+}
+
+// MergeMapTargetKeys is a MergeFunc for maps which merges the keys of the source that are
+// in the target, and ignores the others.
+func MergeMapTargetKeys(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeMapSet(t, s, o, StrategyTargetKeys, func(inT, inS bool) bool {
+		return inT
+	})
+}
+
+// MergeMapNewKeys is a MergeFunc for maps which adds the keys of the source that are not
+// in the target, and keeps the values of those that are.
+func MergeMapNewKeys(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("strategy %s requires maps, got %v", StrategyNewKeys, t.Type())
+	}
+
+	merged := copyMap(t)
+	o.resolveCycles(t, s, merged)
+
+	iter := s.MapRange()
+	for iter.Next() {
+		if !merged.MapIndex(iter.Key()).IsValid() {
+			merged.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+
+	if err := checkSize(reflect.Map, merged.Len(), o); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return merged, nil
+}
+
+// mergeMapSet merges two maps keeping the keys for which keep reports true, given whether
+// the key is in the target and in the source sets.
+func mergeMapSet(t, s reflect.Value, o *Options, name string, keep func(inT, inS bool) bool) (reflect.Value, error) {
+	if t.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("strategy %s requires maps, got %v", name, t.Type())
+	}
+
+	merged := reflect.MakeMapWithSize(t.Type(), t.Len())
+	o.resolveCycles(t, s, merged)
+
+	iter := t.MapRange()
+	for iter.Next() {
+		if err := o.contextErr(); err != nil {
+			return reflect.Value{}, err
+		}
+
+		k, valT := iter.Key(), iter.Value()
+		valS := s.MapIndex(k)
+		inT, inS := isSetMember(valT), isSetMember(valS)
+
+		switch {
+		case keep(inT, inS):
+			val := valT
+			if inT && inS {
+				var err error
+				if val, err = mergeChild(valT, valS, o, fmt.Sprint(k)); err != nil {
+					return reflect.Value{}, fmt.Errorf("key '%s': %w", k, err)
+				}
+			} else if inS {
+				val = valS
+			}
+			merged.SetMapIndex(k, val)
+
+		case !inT:
+			// not a member of the target set, so the entry is kept as is
+			merged.SetMapIndex(k, valT)
+		}
+	}
+
+	if keep(false, true) {
+		iter = s.MapRange()
+		for iter.Next() {
+			k, valS := iter.Key(), iter.Value()
+			if !t.MapIndex(k).IsValid() && isSetMember(valS) {
+				merged.SetMapIndex(k, valS)
+			}
+		}
+	}
+
+	if err := checkSize(reflect.Map, merged.Len(), o); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return merged, nil
+}
+
+// isSetMember reports whether a map value makes its key a member of the set.
+func isSetMember(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Bool {
+		return v.Bool()
+	}
+
+	return true
+}
+
+func copyMap(m reflect.Value) reflect.Value {
+	cp := reflect.MakeMapWithSize(m.Type(), m.Len())
+	iter := m.MapRange()
+	for iter.Next() {
+		cp.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return cp
+}
@@ -0,0 +1,124 @@
+package conjungo
+
+import (
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("map set merge funcs", func() {
+	type set map[string]struct{}
+
+	member := struct{}{}
+
+	DescribeTable("struct{} sets",
+		func(strategy string, expected set) {
+			mf, ok := LookupStrategy(strategy)
+			Expect(ok).To(BeTrue())
+
+			target := set{"a": member, "b": member}
+			source := set{"b": member, "c": member}
+			Expect(applyMergeFunc(mf, target, source)).To(Equal(expected))
+			Expect(target).To(Equal(set{"a": member, "b": member}))
+		},
+		Entry("union", StrategyUnion, set{"a": member, "b": member, "c": member}),
+		Entry("intersect", StrategyIntersect, set{"b": member}),
+		Entry("subtract", StrategySubtract, set{"a": member}),
+		Entry("symmetric difference", StrategySymmetricDifference, set{"a": member, "c": member}),
+		Entry("target keys", StrategyTargetKeys, set{"a": member, "b": member}),
+		Entry("new keys", StrategyNewKeys, set{"a": member, "b": member, "c": member}),
+		Entry("replace", StrategyReplace, set{"b": member, "c": member}),
+	)
+
+	DescribeTable("bool sets",
+		func(mf MergeFunc, expected map[string]bool) {
+			target := map[string]bool{"a": true, "b": true, "off": false, "on": false}
+			source := map[string]bool{"b": true, "c": true, "d": false, "on": true}
+			Expect(applyMergeFunc(mf, target, source)).To(Equal(expected))
+		},
+		Entry("union", MergeMapUnion, map[string]bool{"a": true, "b": true, "c": true, "off": false, "on": true}),
+		Entry("intersect", MergeMapIntersect, map[string]bool{"b": true, "off": false, "on": false}),
+		Entry("subtract", MergeMapSubtract, map[string]bool{"a": true, "off": false, "on": false}),
+		Entry("symmetric difference", MergeMapSymmetricDifference,
+			map[string]bool{"a": true, "c": true, "off": false, "on": true}),
+		Entry("target keys", MergeMapTargetKeys, map[string]bool{"a": true, "b": true, "off": false, "on": false}),
+	)
+
+	DescribeTable("maps of values",
+		func(mf MergeFunc, expected map[string]interface{}) {
+			target := map[string]interface{}{"a": 1, "b": map[string]interface{}{"x": 1}}
+			source := map[string]interface{}{"b": map[string]interface{}{"y": 2}, "c": 3}
+			Expect(applyMergeFunc(mf, target, source)).To(Equal(expected))
+		},
+		Entry("union merges shared keys", MergeMapUnion,
+			map[string]interface{}{"a": 1, "b": map[string]interface{}{"x": 1, "y": 2}, "c": 3}),
+		Entry("intersect merges shared keys", MergeMapIntersect,
+			map[string]interface{}{"b": map[string]interface{}{"x": 1, "y": 2}}),
+		Entry("target keys merges shared keys", MergeMapTargetKeys,
+			map[string]interface{}{"a": 1, "b": map[string]interface{}{"x": 1, "y": 2}}),
+		Entry("new keys keeps shared keys", MergeMapNewKeys,
+			map[string]interface{}{"a": 1, "b": map[string]interface{}{"x": 1}, "c": 3}),
+	)
+
+	It("rejects other kinds", func() {
+		_, err := applyMergeFunc(MergeMapIntersect, 1, 2)
+		Expect(err).To(MatchError("strategy intersect requires maps, got int"))
+
+		_, err = applyMergeFunc(MergeMapNewKeys, []int{}, []int{})
+		Expect(err).To(MatchError("strategy newkeys requires maps, got []int"))
+	})
+
+	It("honors limits", func() {
+		opts := NewOptions()
+		opts.MaxMapKeys = 2
+
+		_, err := MergeMapSymmetricDifference(
+			reflect.ValueOf(set{"a": member, "b": member}), reflect.ValueOf(set{"c": member}), opts)
+		Expect(err).To(BeAssignableToTypeOf(&LimitError{}))
+	})
+
+	It("can be selected per type", func() {
+		type features map[string]bool
+		opts := NewOptions(WithTypeStrategy(reflect.TypeOf(features{}), StrategySubtract))
+
+		target := map[string]interface{}{
+			"enabled": features{"a": true, "b": true},
+			"other":   map[string]bool{"a": true},
+		}
+		source := map[string]interface{}{
+			"enabled": features{"b": true},
+			"other":   map[string]bool{"b": true},
+		}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{
+			"enabled": features{"a": true},
+			"other":   map[string]bool{"a": true, "b": true},
+		}))
+	})
+
+	It("can be selected per path", func() {
+		type roles struct {
+			Granted map[string]struct{}
+			Revoked map[string]struct{}
+		}
+
+		opts, err := LoadPolicy(strings.NewReader(`
+rules:
+  - path: Granted
+    strategy: subtract
+  - path: Revoked
+    strategy: intersect
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := roles{Granted: set{"a": member, "b": member}, Revoked: set{"x": member, "y": member}}
+		err = Merge(&target, roles{Granted: set{"a": member}, Revoked: set{"y": member}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(roles{Granted: set{"b": member}, Revoked: set{"y": member}}))
+	})
+})
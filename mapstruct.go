@@ -0,0 +1,340 @@
+package conjungo
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// mapKeyTags are the struct tags consulted, in order, when matching map keys to struct fields.
+var mapKeyTags = []string{tagKey, "json", "yaml", "mapstructure"}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// mergeMapIntoStruct merges each entry of a map with string keys onto the struct field
+// that the key names. The result is a copy of the target struct.
+// See structFieldsByKey for how keys are matched to fields.
+func mergeMapIntoStruct(valT, valS reflect.Value, opt *Options, prefix string, unmatched *[]string) (reflect.Value, error) {
+	if valS.Type().Key().Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("can not merge map with %v keys into %v", valS.Type().Key(), valT.Type())
+	}
+
+	newT := reflect.New(valT.Type()).Elem()
+	newT.Set(valT)
+
+	fields := structFieldsByKey(valT.Type(), opt)
+
+	// sort the keys so that unmatched keys and errors are reported in a stable order
+	keys := valS.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	for _, k := range keys {
+		key := k.String()
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		index, ok := fields[key]
+		if !ok {
+			index, ok = fields[strings.ToLower(key)]
+		}
+		if !ok {
+			*unmatched = append(*unmatched, path)
+			continue
+		}
+
+		fieldT, ok := fieldByIndexAlloc(newT, index)
+		if !ok {
+			*unmatched = append(*unmatched, path)
+			continue
+		}
+
+		merged, err := mergeFieldsAt(fieldT, valS.MapIndex(k), opt, path, unmatched)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to merge key '%s': %w", key, err)
+		}
+
+		if !merged.IsValid() {
+			continue
+		}
+
+		if fieldT.Kind() != reflect.Interface && fieldT.Type() != merged.Type() {
+			return reflect.Value{}, fmt.Errorf("types dont match %v <> %v", fieldT.Type(), merged.Type())
+		}
+
+		fieldT.Set(merged)
+	}
+
+	return newT, nil
+}
+
+// mergeStructIntoMap writes each exported field of the source struct into a copy of the
+// target map under the field name according to the options, merging with any value already
+// there. Fields tagged `omitempty` are left out if they hold zero values.
+func mergeStructIntoMap(valT, valS reflect.Value, opt *Options, prefix string, unmatched *[]string) (reflect.Value, error) {
+	typT := valT.Type()
+	if typT.Key().Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("can not merge %v into map with %v keys", valS.Type(), typT.Key())
+	}
+
+	newT := reflect.MakeMapWithSize(typT, valT.Len())
+	for _, k := range valT.MapKeys() {
+		newT.SetMapIndex(k, valT.MapIndex(k))
+	}
+
+	for _, fieldS := range cachedStructFields(valS.Type(), opt) {
+		name := fieldS.name
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		valFieldS, err := valS.FieldByIndexErr(fieldS.index)
+		if err != nil || isEmpty(valFieldS) || isOmitted(valFieldS, fieldS.opts) {
+			continue
+		}
+
+		key := reflect.ValueOf(name).Convert(typT.Key())
+
+		existing := newT.MapIndex(key)
+		if !existing.IsValid() {
+			existing = exportSeed(typT.Elem(), valFieldS)
+		}
+
+		merged, err := mergeFieldsAt(existing, valFieldS, opt, path, unmatched)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to merge field `%s.%s`: %w",
+				valS.Type().Name(), name, err)
+		}
+
+		if !merged.IsValid() {
+			continue
+		}
+
+		if typT.Elem().Kind() != reflect.Interface && typT.Elem() != merged.Type() {
+			return reflect.Value{}, fmt.Errorf("types dont match %v <> %v", typT.Elem(), merged.Type())
+		}
+
+		newT.SetMapIndex(key, merged)
+	}
+
+	return newT, nil
+}
+
+// exportSeed returns the empty value of type t that v is merged onto when it is written into
+// a new map entry or slice element. Where t is an interface, plain structs are written out
+// as maps and slices of them as slices of maps.
+func exportSeed(t reflect.Type, v reflect.Value) reflect.Value {
+	switch t.Kind() {
+	case reflect.Map:
+		return reflect.MakeMap(t)
+
+	case reflect.Interface:
+		v = indirectValue(v)
+		if isPlainStruct(v.Type()) {
+			return reflect.ValueOf(map[string]interface{}{})
+		}
+
+		if v.Kind() == reflect.Slice && isPlainStruct(indirectType(v.Type().Elem())) {
+			return reflect.ValueOf([]interface{}{})
+		}
+	}
+
+	return reflect.New(t).Elem()
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isPlainStruct reports whether t is a struct with only exported fields, which can be
+// written out as a map without losing anything.
+func isPlainStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() == 0 {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// indirectValue follows interfaces and pointers until it reaches a concrete value or a nil.
+func indirectValue(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// convertSlice converts each element of the source slice to the element type of the given
+// slice type, so that the result can be merged onto a slice of that type.
+func convertSlice(t reflect.Type, valS reflect.Value, opt *Options, prefix string, unmatched *[]string) (reflect.Value, error) {
+	converted := reflect.MakeSlice(t, 0, valS.Len())
+
+	for i := 0; i < valS.Len(); i++ {
+		elem := exportSeed(t.Elem(), valS.Index(i))
+
+		merged, err := mergeFieldsAt(elem, valS.Index(i), opt, fmt.Sprintf("%s[%d]", prefix, i), unmatched)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+		}
+
+		if !merged.IsValid() {
+			merged = elem
+		}
+
+		converted = reflect.Append(converted, merged)
+	}
+
+	return converted, nil
+}
+
+// convertMap converts each key and value of the source map to the key and element types of
+// the given map type, so that the result can be merged onto a map of that type.
+func convertMap(t reflect.Type, valS reflect.Value, opt *Options, prefix string, unmatched *[]string) (reflect.Value, error) {
+	converted := reflect.MakeMapWithSize(t, valS.Len())
+
+	for _, k := range valS.MapKeys() {
+		key := k
+		if key.Type() != t.Key() {
+			var ok bool
+			var err error
+			if key, ok, err = convertValue(indirectValue(k), t.Key()); err != nil {
+				return reflect.Value{}, err
+			} else if !ok {
+				return reflect.Value{}, fmt.Errorf("can not convert key %v to %v", k, t.Key())
+			}
+		}
+
+		path := fmt.Sprint(k)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		elem := exportSeed(t.Elem(), valS.MapIndex(k))
+		merged, err := mergeFieldsAt(elem, valS.MapIndex(k), opt, path, unmatched)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key '%v': %w", k, err)
+		}
+
+		if !merged.IsValid() {
+			merged = elem
+		}
+
+		converted.SetMapIndex(key, merged)
+	}
+
+	return converted, nil
+}
+
+// convertValue converts a scalar source value to the given type. Numbers are converted
+// between numeric kinds as long as no precision is lost, values are converted between
+// named types of the same kind, and strings are decoded into types implementing
+// encoding.TextUnmarshaler. It reports false if no conversion applies.
+func convertValue(valS reflect.Value, t reflect.Type) (reflect.Value, bool, error) {
+	kindS := valS.Kind()
+	kindT := t.Kind()
+
+	if kindS == reflect.String && reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		ptr := reflect.New(t)
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(valS.String())); err != nil {
+			return reflect.Value{}, false, fmt.Errorf("can not convert %q to %v: %w", valS.String(), t, err)
+		}
+		return ptr.Elem(), true, nil
+	}
+
+	if kindS == kindT && isScalarKind(kindS) {
+		return valS.Convert(t), true, nil
+	}
+
+	if !isNumberKind(kindS) || !isNumberKind(kindT) {
+		return reflect.Value{}, false, nil
+	}
+
+	lossy := fmt.Errorf("can not convert %v to %v without loss", valS.Interface(), t)
+
+	switch kindS {
+	case reflect.Float32, reflect.Float64:
+		f := valS.Float()
+		switch {
+		case isIntKind(kindT):
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || reflect.Zero(t).OverflowInt(int64(f)) {
+				return reflect.Value{}, false, lossy
+			}
+		case isUintKind(kindT):
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || reflect.Zero(t).OverflowUint(uint64(f)) {
+				return reflect.Value{}, false, lossy
+			}
+		default:
+			if reflect.Zero(t).OverflowFloat(f) {
+				return reflect.Value{}, false, lossy
+			}
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := valS.Int()
+		switch {
+		case isIntKind(kindT):
+			if reflect.Zero(t).OverflowInt(i) {
+				return reflect.Value{}, false, lossy
+			}
+		case isUintKind(kindT):
+			if i < 0 || reflect.Zero(t).OverflowUint(uint64(i)) {
+				return reflect.Value{}, false, lossy
+			}
+		}
+
+	default:
+		u := valS.Uint()
+		switch {
+		case isIntKind(kindT):
+			if u > math.MaxInt64 || reflect.Zero(t).OverflowInt(int64(u)) {
+				return reflect.Value{}, false, lossy
+			}
+		case isUintKind(kindT):
+			if reflect.Zero(t).OverflowUint(u) {
+				return reflect.Value{}, false, lossy
+			}
+		}
+	}
+
+	return valS.Convert(t), true, nil
+}
+
+func isScalarKind(k reflect.Kind) bool {
+	return k == reflect.Bool || k == reflect.String || isNumberKind(k)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return isIntKind(k) || isUintKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUintKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package conjungo
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeFields maps and structs", func() {
+	type Server struct {
+		Host    string `json:"host"`
+		Port    int    `yaml:"port"`
+		Timeout time.Duration
+	}
+
+	type Config struct {
+		Name     string
+		Replicas uint8 `mapstructure:"replicas"`
+		Ratio    float64
+		Labels   map[string]string
+		Server   Server
+		Backup   *Server
+		Servers  []Server
+		Started  time.Time
+		Extra    interface{}
+	}
+
+	var (
+		target Config
+		opts   *Options
+	)
+
+	BeforeEach(func() {
+		target = Config{
+			Name:   "target",
+			Labels: map[string]string{"a": "1"},
+			Server: Server{Host: "localhost", Port: 80},
+		}
+		opts = NewOptions()
+	})
+
+	Context("map into struct", func() {
+		It("merges decoded JSON", func() {
+			var source map[string]interface{}
+			err := json.Unmarshal([]byte(`{
+				"name": "source",
+				"replicas": 3,
+				"Ratio": 0.5,
+				"Labels": {"b": "2"},
+				"Server": {"port": 8080},
+				"Backup": {"host": "backup"},
+				"Servers": [{"host": "one"}, {"host": "two", "port": 2}],
+				"Started": "2018-01-02T03:04:05Z",
+				"Extra": {"anything": true}
+			}`), &source)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(target.Name).To(Equal("source"))
+			Expect(target.Replicas).To(Equal(uint8(3)))
+			Expect(target.Ratio).To(Equal(0.5))
+			Expect(target.Labels).To(Equal(map[string]string{"a": "1", "b": "2"}))
+			Expect(target.Server).To(Equal(Server{Host: "localhost", Port: 8080}))
+			Expect(target.Backup).To(Equal(&Server{Host: "backup"}))
+			Expect(target.Servers).To(Equal([]Server{{Host: "one"}, {Host: "two", Port: 2}}))
+			Expect(target.Started).To(Equal(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)))
+			Expect(target.Extra).To(Equal(map[string]interface{}{"anything": true}))
+		})
+
+		It("skips null values", func() {
+			source := map[string]interface{}{"Name": nil}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Name).To(Equal("target"))
+		})
+
+		It("reports unknown keys with ErrorOnUnmatched", func() {
+			opts.ErrorOnUnmatched = true
+			source := map[string]interface{}{
+				"Name":   "source",
+				"nope":   1,
+				"Server": map[string]interface{}{"unknown": 1},
+			}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*UnmatchedFieldsError).Fields).To(Equal([]string{"Server.unknown", "nope"}))
+		})
+
+		DescribeTable("lossy conversions error",
+			func(key string, value interface{}, msg string) {
+				err := MergeFields(&target, map[string]interface{}{key: value}, opts)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(msg))
+				Expect(target.Name).To(Equal("target"))
+			},
+			Entry("fraction into uint", "replicas", 1.5, "can not convert 1.5 to uint8 without loss"),
+			Entry("overflow", "replicas", 256, "can not convert 256 to uint8 without loss"),
+			Entry("negative into uint", "replicas", -1, "can not convert -1 to uint8 without loss"),
+			Entry("bad text", "Started", "yesterday", "can not convert \"yesterday\" to time.Time"),
+			Entry("mismatched kind", "Name", 1, "can not merge int into string"),
+		)
+
+		It("requires string keys", func() {
+			err := MergeFields(&target, map[int]string{1: "a"}, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("can not merge map with int keys into"))
+		})
+	})
+
+	Context("struct into map", func() {
+		It("writes nested maps", func() {
+			target := map[string]interface{}{
+				"Name":   "target",
+				"Server": map[string]interface{}{"Host": "old", "Other": "kept"},
+			}
+
+			source := Config{
+				Name:     "source",
+				Replicas: 2,
+				Server:   Server{Host: "new"},
+				Backup:   &Server{Host: "backup"},
+				Servers:  []Server{{Host: "one"}},
+			}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(target["Name"]).To(Equal("source"))
+			Expect(target["Replicas"]).To(Equal(uint8(2)))
+			Expect(target["Server"]).To(Equal(map[string]interface{}{
+				"Host": "new", "Other": "kept", "Port": 0, "Timeout": time.Duration(0),
+			}))
+			Expect(target["Backup"]).To(HaveKeyWithValue("Host", "backup"))
+			Expect(target["Servers"]).To(Equal([]interface{}{
+				map[string]interface{}{"Host": "one", "Port": 0, "Timeout": time.Duration(0)},
+			}))
+			Expect(target).ToNot(HaveKey("Labels"))
+			Expect(target["Started"]).To(Equal(time.Time{}))
+		})
+
+		It("writes typed maps", func() {
+			type Flags struct {
+				Debug   bool
+				Verbose bool `conjungo:"verbose"`
+				Ignored bool `conjungo:"-"`
+			}
+
+			target := map[string]bool{"Debug": true, "other": true}
+
+			err := MergeFields(&target, Flags{Verbose: true, Ignored: true}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(map[string]bool{"Debug": false, "verbose": true, "other": true}))
+		})
+
+		It("errors on mismatched values", func() {
+			target := map[string]string{}
+
+			err := MergeFields(&target, Server{Port: 1}, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to merge field `Server.Port`: can not merge int into string"))
+		})
+
+		It("names each nested key once in errors", func() {
+			type Inner struct {
+				Port int
+			}
+			type Outer struct {
+				Inner Inner
+			}
+
+			target := Outer{}
+			source := map[string]interface{}{"Inner": map[string]interface{}{"Port": "x"}}
+
+			err := MergeFields(&target, source, opts)
+			Expect(err).To(MatchError(ContainSubstring("failed to merge key 'Inner': failed to merge key 'Port': ")))
+		})
+	})
+})
+
+var _ = Describe("convertValue", func() {
+	DescribeTable("converts without loss",
+		func(source interface{}, t reflect.Type, expected interface{}) {
+			converted, ok, err := convertValue(reflect.ValueOf(source), t)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(converted.Interface()).To(Equal(expected))
+		},
+		Entry("float to int", 3.0, reflect.TypeOf(0), 3),
+		Entry("int to float", 3, reflect.TypeOf(0.0), 3.0),
+		Entry("uint to int", uint(3), reflect.TypeOf(int8(0)), int8(3)),
+		Entry("named string", "ms", reflect.TypeOf(jsonString("")), jsonString("ms")),
+	)
+
+	It("does not convert between unrelated kinds", func() {
+		_, ok, err := convertValue(reflect.ValueOf(true), reflect.TypeOf(""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+type jsonString string
@@ -23,6 +23,63 @@ type Options struct {
 	// using this value as well.
 	ErrorOnUnexported bool
 
+	// If enabled, unexported struct fields are read and set using package unsafe, so that
+	// structs containing them are merged field by field like any other struct. Use with
+	// care: this bypasses the encapsulation of the types involved, such as the state of
+	// a sync.Mutex. ErrorOnUnexported takes precedence over this.
+	MergeUnexported bool
+
+	// If enabled, unexported struct fields keep the value from the target while the exported
+	// fields are merged. ErrorOnUnexported and MergeUnexported take precedence over this.
+	SkipUnexported bool
+
+	// Determines how two non-nil pointers are merged: by replacing the target pointer, by
+	// following them and merging into the value the target points to, or by merging into a
+	// newly allocated value. Defaults to PointerReplace.
+	// Use Options.SetTypePointerPolicy to override this for a particular type.
+	PointerPolicy PointerPolicy
+
+	// Pointers and maps that refer back to themselves are detected during a merge. By
+	// default the target reference is kept as is where a cycle is found, preserving the
+	// cycle in the result. If this is enabled, a CycleError will be returned instead.
+	ErrorOnCycle bool
+
+	// Limits that protect against excessive resource use when merging untrusted input.
+	// A merge that exceeds one of them fails with a LimitError. Zero means no limit.
+	//
+	// MaxDepth limits how deeply nested the merged values can be.
+	// MaxNodes limits the total number of values visited.
+	// MaxSliceLen limits the length of each merged slice.
+	// MaxMapKeys limits the number of keys in each merged map.
+	// The limits also apply within values taken from the source as they are, such as under
+	// a key the target does not have, whose elements, map values and fields count towards
+	// MaxDepth and MaxNodes as though they had been merged.
+	//
+	// Note: the size limits are enforced by the default merge functions. Custom merge
+	// functions for slices and maps should consider enforcing them as well.
+	MaxDepth    int
+	MaxNodes    int
+	MaxSliceLen int
+	MaxMapKeys  int
+
+	// When merging structs of different types with MergeFields, source fields that have no
+	// matching field on the target are ignored by default. If this is enabled, an
+	// UnmatchedFieldsError listing them will be returned instead.
+	ErrorOnUnmatched bool
+
+	// The struct tag used to name struct fields, such as "json" or "yaml". Names are taken
+	// from this tag in error messages and when merging between maps and structs, so that
+	// they match the keys written in the original document. Fields tagged "-" are skipped,
+	// and the `omitempty` and `inline` options are honored. A `conjungo` tag always takes
+	// precedence. If empty, the Go field names are used.
+	TagName string
+
+	// When merging between maps and structs, the fields of embedded structs are matched and
+	// written at the level of the parent if this is enabled, as if tagged `squash`. Otherwise
+	// an embedded struct is treated as a field named after its type. The fields of unexported
+	// embedded structs are always treated as fields of the parent.
+	Squash bool
+
 	// A set of default and customizable functions that define how values are merged
 	// Use the following to define custom merge behavior
 	//		Options.SetTypeMergeFunc(t reflect.Type, mf MergeFunc)
@@ -30,23 +87,56 @@ type Options struct {
 	//		Options.SetDefaultMergeFunc(mf MergeFunc)
 	mergeFuncs *funcSelector
 
-	// To be used by merge functions to pass values down into recursive calls freely
+	// the rules of a Policy, which take precedence over the merge funcs, if any
+	policy *policy
+
+	// set under a policy rule with the conflict mode ConflictError
+	errorOnConflict bool
+
+	// Hooks called before and after each value is merged, for example to audit or redact
+	// values or to enforce policies. BeforeMerge can substitute the values to merge or skip
+	// them altogether. See BeforeMergeFunc and AfterMergeFunc.
+	BeforeMerge BeforeMergeFunc
+	AfterMerge  AfterMergeFunc
+
+	// Receives the log messages emitted during the merge. If nil, they are discarded.
+	Logger Logger
+
+	// To be used by merge functions to pass values down into recursive calls freely.
+	// The merge is aborted with the error of the context once it is cancelled or its
+	// deadline passes. See MergeContext.
 	Context context.Context
+
+	// the state of the merge in progress, if any
+	state *mergeState
 }
 
 // NewOptions generates default Options. Overwrite is set to true, and a set of
-// default merge function definitions are added.
-func NewOptions() *Options {
-	return &Options{
+// default merge function definitions are added. The given Option funcs are then
+// applied in order. See Option.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{
 		Overwrite:  true,
 		mergeFuncs: newFuncSelector(),
 	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
 }
 
 // SetTypeMergeFunc is used to define a custom merge func that will be used to merge two
 // items of a particular type. Accepts the reflect.Type representation of the type and
 // the MergeFunc to merge it.
-// This is useful for defining specific merge behavior of things such as specific struct types
+// This is useful for defining specific merge behavior of things such as specific struct types.
+// A merge func for a type is also used to merge the values pointed to by pointers to it,
+// unless there is one for the pointer type itself or the pointer policy for the pointers
+// is PointerReplace.
+// If the type is an interface, the merge func is used for any type implementing it that
+// has no merge func of its own. Where a type implements several such interfaces, the one
+// whose merge func was set first is used.
 func (o *Options) SetTypeMergeFunc(t reflect.Type, mf MergeFunc) {
 	o.mergeFuncs.setTypeMergeFunc(t, mf)
 }
@@ -55,7 +145,7 @@ func (o *Options) SetTypeMergeFunc(t reflect.Type, mf MergeFunc) {
 // items of a particular kind. Accepts reflect.Kind and the MergeFunc to merge it.
 // This is useful for defining more general merge behavior, for instance
 // merge all maps or structs in a particular way.
-// A default merge behavior is predefined for map, slice and struct when using NewOptions()
+// A default merge behavior is predefined for map, slice, array and struct when using NewOptions()
 func (o *Options) SetKindMergeFunc(k reflect.Kind, mf MergeFunc) {
 	o.mergeFuncs.setKindMergeFunc(k, mf)
 }
@@ -71,12 +161,51 @@ func (o *Options) SetDefaultMergeFunc(mf MergeFunc) {
 
 var valType = reflect.TypeOf(reflect.Value{})
 
+type mergeRootFunc func(valT, valS reflect.Value, opt *Options) (reflect.Value, error)
+
 // Merge the given source onto the given target following the options given. The target value
 // must be a pointer. If opt is nil, defaults will be used. If an error occurs during
 // the merge process the target will be unmodified. Merge will accept any two entities,
 // as long as their types are the same.
 // See Options and MergeFunc for further customization possibilities.
 func Merge(target, source interface{}, opt *Options) error {
+	return mergeRoot(target, source, opt, merge)
+}
+
+// MergeContext is like Merge, but uses the given context for the merge. The merge is aborted
+// with the error of the context once it is cancelled or its deadline passes, leaving the
+// target unmodified. Any Context already set on the options is replaced.
+//
+// Note that with PointerFollow, values the target points to are modified as the merge
+// progresses, so that they may be left partially merged when it is aborted.
+func MergeContext(ctx context.Context, target, source interface{}, opt *Options) error {
+	if opt == nil {
+		opt = NewOptions()
+	}
+
+	withCtx := *opt
+	withCtx.Context = ctx
+
+	return Merge(target, source, &withCtx)
+}
+
+// contextErr returns the error of the options context if it is done.
+func (o *Options) contextErr() error {
+	if o.Context == nil {
+		return nil
+	}
+
+	select {
+	case <-o.Context.Done():
+		return o.Context.Err()
+	default:
+		return nil
+	}
+}
+
+// mergeRoot validates the entry point arguments, runs the given merge on them and
+// writes the result to target.
+func mergeRoot(target, source interface{}, opt *Options, mergeFn mergeRootFunc) error {
 	vT := reflect.ValueOf(target)
 	vS := reflect.ValueOf(source)
 
@@ -104,14 +233,20 @@ func Merge(target, source interface{}, opt *Options) error {
 		return errors.New("invalid options, use NewOptions() to generate and then modify as needed")
 	}
 
+	opt = opt.withState()
+
 	//make a copy here so if there is an error mid way, the target stays in tact
 	cp := vT.Elem()
 
-	merged, err := merge(cp, reflect.Indirect(vS), opt)
+	merged, err := mergeFn(cp, reflect.Indirect(vS), opt)
 	if err != nil {
 		return err
 	}
 
+	if err := opt.contextErr(); err != nil {
+		return err
+	}
+
 	if !isSettable(vT.Elem(), merged) {
 		return fmt.Errorf("Merge failed: expected merged result to be %v but got %v",
 			vT.Elem().Type(), merged.Type())
@@ -130,13 +265,62 @@ func isSettable(t, s reflect.Value) bool {
 }
 
 func merge(valT, valS reflect.Value, opt *Options) (reflect.Value, error) {
+	return mergeWith(valT, valS, opt, nil)
+}
+
+// mergeWith merges two values with the given merge func, or the merge func for their type
+// if it is nil.
+func mergeWith(valT, valS reflect.Value, opt *Options, mf MergeFunc) (reflect.Value, error) {
+	if err := opt.contextErr(); err != nil {
+		return reflect.Value{}, err
+	}
+
+	opt = opt.withState()
+
+	leave, err := opt.state.enter(opt)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	defer leave()
+
 	// if source is nil, skip
 	if isEmpty(valS) {
 		return valT, nil
 	}
 
+	if opt.BeforeMerge != nil {
+		valT, valS, err = opt.BeforeMerge(opt.state.path, valT, valS)
+		if errors.Is(err, SkipMerge) {
+			return valT, nil
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	val, err := mergeValues(valT, valS, opt, mf)
+
+	if opt.AfterMerge != nil {
+		return opt.AfterMerge(opt.state.path, valT, valS, val, err)
+	}
+
+	return val, err
+}
+
+// mergeValues merges two values with the given merge func, or the merge func for their type
+// if it is nil.
+func mergeValues(valT, valS reflect.Value, opt *Options, mf MergeFunc) (reflect.Value, error) {
+	// a substituted source may be nil
+	if isEmpty(valS) {
+		return valT, nil
+	}
+
 	// if target is nil write to it
 	if isEmpty(valT) {
+		if err := checkSizes(valS, opt); err != nil {
+			return reflect.Value{}, err
+		}
+
 		return valS, nil
 	}
 
@@ -151,13 +335,55 @@ func merge(valT, valS reflect.Value, opt *Options) (reflect.Value, error) {
 		return reflect.Value{}, fmt.Errorf("Types do not match: %v, %v", valT.Type(), valS.Type())
 	}
 
+	// references are only merged once, and a reference that is already being merged is a cycle
+	key, isRef := referenceKey(valT, valS)
+	if isRef {
+		if val, ok := opt.state.visited[key]; ok {
+			return val, nil
+		}
+
+		if val, ok := opt.state.visiting[key]; ok {
+			if opt.ErrorOnCycle {
+				return reflect.Value{}, &CycleError{Type: valT.Type()}
+			}
+
+			return val, nil
+		}
+
+		opt.state.visiting[key] = valT
+		defer delete(opt.state.visiting, key)
+	}
+
 	// look for a merge function
-	f := opt.mergeFuncs.getFunc(valT)
+	f := mf
+	if f == nil && opt.policy != nil {
+		f = opt.policy.funcFor(opt.state.path, valT.Type())
+	}
+	if f == nil {
+		f = opt.mergeFuncs.getFunc(valT)
+	}
+
+	// only trace when a logger is set, to avoid the cost of looking up func names
+	if opt.Logger != nil {
+		opt.Logger.Tracef("enter %v at depth %d: selected %s", valT.Type(), opt.state.depth, funcName(f))
+	}
+
 	val, err := f(valT, valS, opt)
 	if err != nil {
+		if opt.Logger != nil {
+			opt.Logger.Tracef("leave %v at depth %d: error: %v", valT.Type(), opt.state.depth, err)
+		}
 		return reflect.Value{}, err
 	}
 
+	if opt.Logger != nil {
+		opt.Logger.Tracef("leave %v at depth %d: result: %v", valT.Type(), opt.state.depth, val)
+	}
+
+	if isRef {
+		opt.state.visited[key] = val
+	}
+
 	return val, nil
 }
 
@@ -1,18 +1,23 @@
 package conjungo
 
 import (
+	"reflect"
 	"testing"
 
-	"github.com/sirupsen/logrus"
-
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 func TestMergeSuite(t *testing.T) {
-	// reduce the noise when testing
-	logrus.SetLevel(logrus.FatalLevel)
-
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Merge Suite")
 }
+
+// applyMergeFunc merges the source onto the target with the merge func and default options.
+func applyMergeFunc(mf MergeFunc, target, source interface{}) (interface{}, error) {
+	merged, err := mf(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+	if err != nil {
+		return nil, err
+	}
+	return merged.Interface(), nil
+}
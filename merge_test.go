@@ -1,6 +1,7 @@
 package conjungo
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"reflect"
@@ -736,6 +737,65 @@ var _ = Describe("isEmpty", func() {
 	})
 })
 
+var _ = Describe("MergeContext", func() {
+	type Item struct {
+		Name string
+		Size int
+	}
+
+	var (
+		target, source map[string]interface{}
+		opts           *Options
+	)
+
+	BeforeEach(func() {
+		target = map[string]interface{}{"a": Item{Name: "a"}, "b": []int{1}}
+		source = map[string]interface{}{"a": Item{Size: 1}, "b": []int{2}, "c": "new"}
+		opts = NewOptions()
+	})
+
+	It("merges with a live context", func() {
+		err := MergeContext(context.Background(), &target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{
+			"a": Item{Size: 1}, "b": []int{1, 2}, "c": "new",
+		}))
+		Expect(opts.Context).To(BeNil())
+	})
+
+	It("does not merge with a cancelled context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := MergeContext(ctx, &target, source, opts)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(target).To(Equal(map[string]interface{}{"a": Item{Name: "a"}, "b": []int{1}}))
+	})
+
+	It("aborts mid merge leaving the target intact", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		opts.SetTypeMergeFunc(reflect.TypeOf(0), func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+			cancel()
+			return s, nil
+		})
+
+		err := MergeContext(ctx, &target, source, opts)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(target).To(Equal(map[string]interface{}{"a": Item{Name: "a"}, "b": []int{1}}))
+	})
+
+	It("honors a deadline set on the options", func() {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		opts.Context = ctx
+
+		err := Merge(&target, source, opts)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
+
 func erroringMergeFunc(t, s reflect.Value, o *Options) (reflect.Value, error) {
 	return reflect.Value{}, errors.New("returns error")
 }
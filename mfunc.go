@@ -3,8 +3,8 @@ package conjungo
 import (
 	"fmt"
 	"reflect"
-
-	"github.com/sirupsen/logrus"
+	"sync"
+	"unsafe"
 )
 
 // A MergeFunc defines how two items are merged together. It should accept a reflect.Value
@@ -16,57 +16,249 @@ import (
 type MergeFunc func(target, source reflect.Value, o *Options) (reflect.Value, error)
 
 type funcSelector struct {
+	// guards the fields below, so that merge funcs can be set while merges are running
+	mu sync.RWMutex
+
 	typeFuncs   map[reflect.Type]MergeFunc
 	kindFuncs   map[reflect.Kind]MergeFunc
 	defaultFunc MergeFunc
+
+	// the kinds with merge funcs set by SetKindMergeFunc, which take precedence over the
+	// defaults for standard library types
+	customKinds map[reflect.Kind]bool
+
+	// the interface types with merge funcs in typeFuncs, in the order they were set
+	ifaceTypes []reflect.Type
+
+	ptrPolicies map[reflect.Type]PointerPolicy
+
+	// cached per type, and replaced when the merge funcs change
+	plans *typePlans
+
+	// set once the options are frozen, after which nothing can be changed
+	frozen bool
 }
 
+// stdlibFuncs holds the default merge funcs for standard library types, which are used for
+// those with no merge func set for their type, an interface they implement or their kind.
+var stdlibFuncs = stdlibTypeFuncs()
+
+// builtinFuncs holds the merge funcs set by NewOptions, which UsesDefaultMergeFuncs compares
+// with those of the options.
+var builtinFuncs = newFuncSelector()
+
 func newFuncSelector() *funcSelector {
 	return &funcSelector{
-		typeFuncs: map[reflect.Type]MergeFunc{},
+		typeFuncs: map[reflect.Type]MergeFunc{
+			optionalIface:   mergeOptional,
+			orderedMapIface: mergeOrderedMap,
+			yamlNodeType:    mergeYAMLNode,
+			yamlNodePtrType: mergeElemsFunc(mergeYAMLNode),
+		},
 		kindFuncs: map[reflect.Kind]MergeFunc{
 			reflect.Map:    mergeMap,
 			reflect.Slice:  mergeSlice,
+			reflect.Array:  mergeArray,
 			reflect.Struct: mergeStruct,
+			reflect.Ptr:    mergePtr,
 		},
 		defaultFunc: defaultMergeFunc,
+		ifaceTypes:  []reflect.Type{optionalIface, orderedMapIface},
+		plans:       newTypePlans(),
+	}
+}
+
+// lock locks the selector for changes, which are not allowed once it is frozen.
+func (f *funcSelector) lock() {
+	f.mu.Lock()
+	if f.frozen {
+		f.mu.Unlock()
+		panic("conjungo: options are frozen, use Clone to get a copy that can be modified")
 	}
 }
 
 func (f *funcSelector) setTypeMergeFunc(t reflect.Type, mf MergeFunc) {
+	f.lock()
+	defer f.mu.Unlock()
+
 	if nil == f.typeFuncs {
 		f.typeFuncs = map[reflect.Type]MergeFunc{}
 	}
+	if _, ok := f.typeFuncs[t]; !ok && t.Kind() == reflect.Interface {
+		f.ifaceTypes = append(f.ifaceTypes, t)
+	}
 	f.typeFuncs[t] = mf
+	f.plans = newTypePlans()
 }
 
 func (f *funcSelector) setKindMergeFunc(k reflect.Kind, mf MergeFunc) {
+	f.lock()
+	defer f.mu.Unlock()
+
 	if nil == f.kindFuncs {
 		f.kindFuncs = map[reflect.Kind]MergeFunc{}
 	}
 	f.kindFuncs[k] = mf
+	if nil == f.customKinds {
+		f.customKinds = map[reflect.Kind]bool{}
+	}
+	f.customKinds[k] = true
+	f.plans = newTypePlans()
 }
 
 func (f *funcSelector) setDefaultMergeFunc(mf MergeFunc) {
+	f.lock()
+	defer f.mu.Unlock()
+
 	f.defaultFunc = mf
+	f.plans = newTypePlans()
+}
+
+func (f *funcSelector) setTypePointerPolicy(t reflect.Type, p PointerPolicy) {
+	f.lock()
+	defer f.mu.Unlock()
+
+	if nil == f.ptrPolicies {
+		f.ptrPolicies = map[reflect.Type]PointerPolicy{}
+	}
+	f.ptrPolicies[t] = p
+}
+
+// Looks up the pointer policy defined for a pointer type, or else for the type it points to.
+func (f *funcSelector) getTypePointerPolicy(t reflect.Type) (PointerPolicy, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if p, ok := f.ptrPolicies[t]; ok {
+		return p, true
+	}
+
+	p, ok := f.ptrPolicies[t.Elem()]
+	return p, ok
+}
+
+// getPlans returns the plans cached for the current merge funcs.
+func (f *funcSelector) getPlans() *typePlans {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.plans
+}
+
+func (f *funcSelector) freeze() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.frozen = true
+}
+
+// clone returns a copy of the selector which can be modified independently.
+func (f *funcSelector) clone() *funcSelector {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	cp := &funcSelector{
+		typeFuncs:   make(map[reflect.Type]MergeFunc, len(f.typeFuncs)),
+		kindFuncs:   make(map[reflect.Kind]MergeFunc, len(f.kindFuncs)),
+		defaultFunc: f.defaultFunc,
+		ifaceTypes:  append([]reflect.Type(nil), f.ifaceTypes...),
+		customKinds: make(map[reflect.Kind]bool, len(f.customKinds)),
+		ptrPolicies: make(map[reflect.Type]PointerPolicy, len(f.ptrPolicies)),
+		plans:       newTypePlans(),
+	}
+
+	for t, mf := range f.typeFuncs {
+		cp.typeFuncs[t] = mf
+	}
+	for k, mf := range f.kindFuncs {
+		cp.kindFuncs[k] = mf
+	}
+	for k := range f.customKinds {
+		cp.customKinds[k] = true
+	}
+	for t, p := range f.ptrPolicies {
+		cp.ptrPolicies[t] = p
+	}
+
+	return cp
 }
 
 // Get func must always return a function.
 // First looks for a merge func defined for its type. Type is the most specific way to categorize something,
-// for example, struct type foo of package bar or map[string]string. Next it looks for a merge func defined for its
-// kind, for example, struct or map. At this point, if nothing matches, it will fall back to the default merge definition.
+// for example, struct type foo of package bar or map[string]string. For a pointer, it next looks for a merge func
+// defined for the type it points to, which is used to merge the values pointed to unless the pointer policy is
+// PointerReplace. Then it looks for a merge func
+// defined for an interface the type implements, in the order they were defined. Next it looks for a merge func set
+// for its kind with SetKindMergeFunc, then the default for a standard library type such as time.Time, then the
+// predefined merge func for its kind, for example, struct or map. At this point, if nothing matches, it will fall back
+// to the default merge definition.
+// The func found is cached for the type until the merge funcs change.
 func (f *funcSelector) getFunc(v reflect.Value) MergeFunc {
-	// prioritize a specific 'type' definition
-	ti := v.Type()
+	return f.getTypeFunc(v.Type())
+}
+
+// getTypeFunc returns the merge func for values of the type, as described for getFunc.
+func (f *funcSelector) getTypeFunc(ti reflect.Type) MergeFunc {
+
+	if plans := f.getPlans(); plans != nil {
+		if fx, ok := plans.funcs.Load(ti); ok {
+			return fx.(MergeFunc)
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 
+	fx := f.lookupFunc(ti)
+	if f.plans != nil {
+		// cached while the lock is held, so the plans still match the merge funcs
+		f.plans.funcs.Store(ti, fx)
+	}
+	return fx
+}
+
+// lookupFunc finds the merge func for the type as described for getFunc. It must be called
+// with the lock held.
+func (f *funcSelector) lookupFunc(ti reflect.Type) MergeFunc {
+	// prioritize a specific 'type' definition
 	if fx, ok := f.typeFuncs[ti]; ok {
 		return fx
 	}
 
-	// then look for a more general 'kind'.
-	if fx, ok := f.kindFuncs[ti.Kind()]; ok {
+	// then the type pointed to, unless pointers are replaced
+	if ti.Kind() == reflect.Ptr {
+		if fx, ok := f.typeFuncs[ti.Elem()]; ok {
+			return mergePointeeFunc(fx)
+		}
+	}
+
+	// then an interface implemented by the type
+	for _, iface := range f.ifaceTypes {
+		if ti.Implements(iface) {
+			return f.typeFuncs[iface]
+		}
+	}
+
+	// then look for a more general 'kind' set by the user
+	kindFunc, hasKindFunc := f.kindFuncs[ti.Kind()]
+	if hasKindFunc && f.customKinds[ti.Kind()] {
+		return kindFunc
+	}
+
+	// then the defaults for standard library types, which are merged as single values
+	if fx, ok := stdlibFuncs[ti]; ok {
 		return fx
 	}
+	if ti.Kind() == reflect.Ptr {
+		if fx, ok := stdlibFuncs[ti.Elem()]; ok {
+			return mergePointeeFunc(fx)
+		}
+	}
+
+	// then the predefined funcs for kinds
+	if hasKindFunc {
+		return kindFunc
+	}
 
 	if f.defaultFunc != nil {
 		return f.defaultFunc
@@ -77,7 +269,20 @@ func (f *funcSelector) getFunc(v reflect.Value) MergeFunc {
 
 // The most basic merge function to be used as default behavior.
 // In overwrite mode, it returns the source. Otherwise, it returns the target.
+// Under a policy rule with the conflict mode ConflictModeError, it returns whichever is not a
+// zero value, and an error if both are set and differ.
 func defaultMergeFunc(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if o.errorOnConflict {
+		switch {
+		case s.IsZero():
+			return t, nil
+		case t.IsZero() || reflect.DeepEqual(t.Interface(), s.Interface()):
+			return s, nil
+		}
+
+		return reflect.Value{}, &ConflictError{Path: o.Path(), Target: t.Interface(), Source: s.Interface()}
+	}
+
 	if o.Overwrite {
 		return s, nil
 	}
@@ -92,22 +297,49 @@ func mergeMap(t, s reflect.Value, o *Options) (v reflect.Value, err error) {
 
 	keys := s.MapKeys()
 
+	if o.MaxMapKeys > 0 {
+		n := t.Len()
+		for _, k := range keys {
+			if !t.MapIndex(k).IsValid() {
+				n++
+			}
+		}
+
+		if err := checkSize(reflect.Map, n, o); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("failed to merge map: %v", r)
 		}
 	}()
 
+	// merge into a copy so that the target stays intact if the merge is aborted
+	newT := reflect.MakeMapWithSize(t.Type(), t.Len())
+	iter := t.MapRange()
+	for iter.Next() {
+		newT.SetMapIndex(iter.Key(), iter.Value())
+	}
+	o.resolveCycles(t, s, newT)
+
 	for _, k := range keys {
-		logrus.Debugf("MERGE T<>S '%s' :: %v <> %v", k, t.MapIndex(k), s.MapIndex(k))
-		val, err := merge(t.MapIndex(k), s.MapIndex(k), o)
+		if err := o.contextErr(); err != nil {
+			return reflect.Value{}, err
+		}
+
+		if o.Logger != nil {
+			o.Logger.Debugf("MERGE T<>S '%s' :: %v <> %v", k, t.MapIndex(k), s.MapIndex(k))
+		}
+		val, err := mergeChild(t.MapIndex(k), s.MapIndex(k), o, fmt.Sprint(k))
 		if err != nil {
-			return reflect.Value{}, fmt.Errorf("key '%s': %v", k, err)
+			return reflect.Value{}, fmt.Errorf("key '%s': %w", k, err)
 		}
-		t.SetMapIndex(k, val)
+		newT.SetMapIndex(k, val)
 	}
 
-	v = t
+	v = newT
 	return
 }
 
@@ -117,9 +349,84 @@ func mergeSlice(t, s reflect.Value, o *Options) (reflect.Value, error) {
 		return reflect.Value{}, fmt.Errorf("slices must have same type: T: %v S: %v", t.Type(), s.Type())
 	}
 
+	if err := checkSize(reflect.Slice, t.Len()+s.Len(), o); err != nil {
+		return reflect.Value{}, err
+	}
+
+	// the appended elements are taken as is
+	if err := checkSizes(s, o); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if err := o.contextErr(); err != nil {
+		return reflect.Value{}, err
+	}
+
 	return reflect.AppendSlice(t, s), nil
 }
 
+// Merges two arrays of the same type element by element. A zero value element in the source
+// leaves the target element as is, and a zero value element in the target is replaced by the
+// source element. Other elements are merged with the merge func for their type.
+func mergeArray(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() != reflect.Array || t.Type() != s.Type() {
+		return reflect.Value{}, fmt.Errorf("arrays must have same type: T: %v S: %v", t.Type(), s.Type())
+	}
+
+	newT := reflect.New(t.Type()).Elem()
+	newT.Set(t)
+
+	for i := 0; i < s.Len(); i++ {
+		if err := o.contextErr(); err != nil {
+			return reflect.Value{}, err
+		}
+
+		elemT := t.Index(i)
+		elemS := s.Index(i)
+		if o.Logger != nil {
+			o.Logger.Debugf("MERGE T<>S [%d] :: %v <> %v", i, elemT, elemS)
+		}
+
+		if elemS.IsZero() {
+			continue
+		}
+
+		if elemT.IsZero() {
+			newT.Index(i).Set(elemS)
+			continue
+		}
+
+		merged, err := mergeChild(elemT, elemS, o, fmt.Sprintf("[%d]", i))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+		}
+
+		if !merged.IsValid() {
+			continue
+		}
+
+		if elemT.Kind() != reflect.Interface && elemT.Type() != merged.Type() {
+			return reflect.Value{}, fmt.Errorf("types dont match %v <> %v", elemT.Type(), merged.Type())
+		}
+
+		newT.Index(i).Set(merged)
+	}
+
+	return newT, nil
+}
+
+// MergeStruct is the default MergeFunc for structs, which merges them field by field. Merge
+// funcs set for struct types can use it to fall back on merging their fields with conjungo,
+// such as those generated by conjungo-gen when the options require it.
+func MergeStruct(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if o.state == nil {
+		// called outside of a merge, so merge the structs as its root
+		return mergeWith(t, s, o, mergeStruct)
+	}
+
+	return mergeStruct(t, s, o)
+}
+
 // This func is designed to be called by merge().
 // It should not be used on its own because it will panic.
 func mergeStruct(t, s reflect.Value, o *Options) (reflect.Value, error) {
@@ -129,39 +436,122 @@ func mergeStruct(t, s reflect.Value, o *Options) (reflect.Value, error) {
 	kindT := valT.Kind()
 	kindS := valS.Kind()
 
-	newT := reflect.New(valT.Type()).Elem() //a new instance of the struct type that can be set
-
 	okT := kindT == reflect.Struct
 	okS := kindS == reflect.Struct
 	if !okT || !okS {
 		return reflect.Value{}, fmt.Errorf("got non-struct kind (tagret: %v; source: %v)", kindT, kindS)
 	}
 
-	for i := 0; i < valS.NumField(); i++ {
+	newT := reflect.New(valT.Type()).Elem() //a new instance of the struct type that can be set
+	newT.Set(valT)                          // start from the target so skipped fields keep its values
+
+	if o.MergeUnexported {
+		// unexported fields can only be accessed through addressable values
+		valT = addressable(valT)
+		valS = addressable(valS)
+	}
+
+	fallback, err := mergeStructFieldsInto(newT, valT, valS, o)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if fallback {
+		// revert to using the default func instead to treat the struct as single entity
+		return defaultMergeFunc(t, s, o)
+	}
+
+	return newT, nil
+}
+
+// mergeStructFieldsInto merges each field of valS onto the same field of valT and sets the
+// result on newT, a settable struct of the same type. It reports true if a field can not be
+// set, in which case the struct should be treated as a single entity.
+func mergeStructFieldsInto(newT, valT, valS reflect.Value, o *Options) (bool, error) {
+	for i, plan := range structFieldPlans(newT.Type(), o) {
+		if err := o.contextErr(); err != nil {
+			return false, err
+		}
+
 		fieldT := newT.Field(i)
-		logrus.Debugf("merging struct field %s", fieldT)
+		field := plan.field
+		if o.Logger != nil {
+			o.Logger.Debugf("merging struct field %s", fieldT)
+		}
 
 		// field is addressable because it's created above. So this means it is unexported.
-		if !fieldT.CanSet() {
-			if o.ErrorOnUnexported {
-				return reflect.Value{}, fmt.Errorf("struct of type %v has unexported field: %s",
-					t.Type().Name(), newT.Type().Field(i).Name)
+		if !plan.exported {
+			// the fields promoted from an unexported embedded struct can still be set
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				fallback, err := mergeStructFieldsInto(fieldT, valT.Field(i), valS.Field(i), o)
+				if fallback || err != nil {
+					return fallback, err
+				}
+				continue
+			}
+
+			switch {
+			case o.ErrorOnUnexported:
+				return false, fmt.Errorf("struct of type %v has unexported field: %s",
+					newT.Type().Name(), field.Name)
+
+			case o.MergeUnexported:
+				merged, err := mergeChild(exposeField(valT.Field(i)), exposeField(valS.Field(i)), o, field.Name)
+				if err != nil {
+					return false, fmt.Errorf("failed to merge field `%s.%s`: %w",
+						newT.Type().Name(), field.Name, err)
+				}
+
+				if !merged.IsValid() {
+					continue
+				}
+
+				if fieldT.Kind() != reflect.Interface && fieldT.Type() != merged.Type() {
+					return false, fmt.Errorf("types dont match %v <> %v", fieldT.Type(), merged.Type())
+				}
+
+				exposeField(fieldT).Set(merged)
+				continue
+
+			case o.SkipUnexported:
+				// newT already holds the target value
+				continue
 			}
 
-			// revert to using the default func instead to treat the struct as single entity
-			return defaultMergeFunc(t, s, o)
+			return true, nil
+		}
+
+		name := plan.name
+
+		var merged reflect.Value
+		var err error
+		if plan.strategy != "" {
+			var mf MergeFunc
+			if mf, err = strategy(plan.strategy); err == nil {
+				mf = adaptStrategy(mf, field.Type)
+				merged, err = mergeChildWith(valT.Field(i), valS.Field(i), o, name, mf)
+			}
+		} else if plan.embeddedPtr && o.pointerPolicy(field.Type) != PointerFollow &&
+			!valT.Field(i).IsNil() && !valS.Field(i).IsNil() {
+			// embedded pointers are merged into a new value unless they are to be followed
+			merged, err = mergeChild(valT.Field(i).Elem(), valS.Field(i).Elem(), o, name)
+			if err == nil && merged.IsValid() {
+				ptr := reflect.New(field.Type.Elem())
+				ptr.Elem().Set(merged)
+				merged = ptr
+			}
+		} else {
+			merged, err = mergeChild(valT.Field(i), valS.Field(i), o, name)
 		}
 
-		//fieldT should always be valid because it's created above
-		merged, err := merge(valT.Field(i), valS.Field(i), o)
 		if err != nil {
-			return reflect.Value{}, fmt.Errorf("failed to merge field `%s.%s`: %v",
-				newT.Type().Name(), newT.Type().Field(i).Name, err)
+			return false, fmt.Errorf("failed to merge field `%s.%s`: %w",
+				newT.Type().Name(), name, err)
 		}
 
 		if !merged.IsValid() {
-			logrus.Warnf("merged value is invalid for field %s. Falling back to default merge: %v <> %v",
-				newT.Type().Field(i).Name, valT.Field(i), valS.Field(i))
+			o.logger().Warnf("merged value is invalid for field %s. Falling back to default merge: %v <> %v",
+				field.Name, valT.Field(i), valS.Field(i))
 
 			// if merge returned an invalid value, fallback to a default merge for the field
 			// defaultMergeFun() does not error
@@ -169,11 +559,35 @@ func mergeStruct(t, s reflect.Value, o *Options) (reflect.Value, error) {
 		}
 
 		if fieldT.Kind() != reflect.Interface && fieldT.Type() != merged.Type() {
-			return reflect.Value{}, fmt.Errorf("types dont match %v <> %v", fieldT.Type(), merged.Type())
+			return false, fmt.Errorf("types dont match %v <> %v", fieldT.Type(), merged.Type())
 		}
 
 		fieldT.Set(merged)
 	}
 
-	return newT, nil
+	return false, nil
+}
+
+func isEmbeddedStructPtr(f reflect.StructField) bool {
+	return f.Anonymous && f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct
+}
+
+// addressable returns v if it is addressable, or an addressable copy of it otherwise.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+
+	cp := reflect.New(v.Type()).Elem()
+	cp.Set(v)
+	return cp
+}
+
+// exposeField returns a settable version of an addressable unexported struct field.
+func exposeField(v reflect.Value) reflect.Value {
+	if v.CanSet() {
+		return v
+	}
+
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
 }
@@ -1,7 +1,9 @@
 package conjungo
 
 import (
+	"fmt"
 	"reflect"
+	"sync"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -30,9 +32,17 @@ var _ = Describe("newFuncSelector", func() {
 		Expect(sliceOK).To(BeTrue())
 		Expect(sliceMerge).ToNot(BeNil())
 
+		arrayMerge, arrayOK := fs.kindFuncs[reflect.Array]
+		Expect(arrayOK).To(BeTrue())
+		Expect(arrayMerge).ToNot(BeNil())
+
 		structMerge, structOK := fs.kindFuncs[reflect.Struct]
 		Expect(structOK).To(BeTrue())
 		Expect(structMerge).ToNot(BeNil())
+
+		ptrMerge, ptrOK := fs.kindFuncs[reflect.Ptr]
+		Expect(ptrOK).To(BeTrue())
+		Expect(ptrMerge).ToNot(BeNil())
 	})
 
 	It("has default mergeFunc", func() {
@@ -177,6 +187,169 @@ var _ = Describe("GetFunc", func() {
 	})
 })
 
+type versioned interface {
+	Version() int
+}
+
+type versionedDoc struct {
+	Rev  int
+	Body string
+}
+
+func (d versionedDoc) Version() int { return d.Rev }
+
+func (d versionedDoc) String() string { return d.Body }
+
+type versionedPtrDoc struct {
+	Rev int
+}
+
+func (d *versionedPtrDoc) Version() int { return d.Rev }
+
+// keeps the value with the highest version
+func mergeVersioned(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if s.Interface().(versioned).Version() > t.Interface().(versioned).Version() {
+		return s, nil
+	}
+	return t, nil
+}
+
+var _ = Describe("GetFunc precedence", func() {
+	var (
+		fs           *funcSelector
+		versionedTyp = reflect.TypeOf((*versioned)(nil)).Elem()
+		stringerTyp  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+		docTyp       = reflect.TypeOf(versionedDoc{})
+	)
+
+	selected := func(v interface{}) string {
+		returned, _ := fs.getFunc(reflect.ValueOf(v))(reflect.Value{}, reflect.Value{}, NewOptions())
+		return returned.Interface().(string)
+	}
+
+	BeforeEach(func() {
+		fs = newFuncSelector()
+		fs.setDefaultMergeFunc(newMergeFuncStub("default"))
+		fs.setKindMergeFunc(reflect.Struct, newMergeFuncStub("kind"))
+	})
+
+	It("uses a func for an implemented interface over the kind", func() {
+		fs.setTypeMergeFunc(versionedTyp, newMergeFuncStub("versioned"))
+		Expect(selected(versionedDoc{})).To(Equal("versioned"))
+		Expect(selected(&versionedPtrDoc{})).To(Equal("versioned"))
+		Expect(selected(versionedPtrDoc{})).To(Equal("kind"))
+		Expect(selected(struct{}{})).To(Equal("kind"))
+		Expect(selected(1)).To(Equal("default"))
+	})
+
+	It("uses the interface set first", func() {
+		fs.setTypeMergeFunc(stringerTyp, newMergeFuncStub("stringer"))
+		fs.setTypeMergeFunc(versionedTyp, newMergeFuncStub("versioned"))
+		Expect(selected(versionedDoc{})).To(Equal("stringer"))
+
+		// setting it again keeps its place
+		fs.setTypeMergeFunc(versionedTyp, newMergeFuncStub("versioned again"))
+		Expect(selected(versionedDoc{})).To(Equal("stringer"))
+		Expect(selected(&versionedPtrDoc{})).To(Equal("versioned again"))
+	})
+
+	It("uses a func for the exact type over the interface", func() {
+		fs.setTypeMergeFunc(versionedTyp, newMergeFuncStub("versioned"))
+		fs.setTypeMergeFunc(docTyp, newMergeFuncStub("type"))
+		Expect(selected(versionedDoc{})).To(Equal("type"))
+	})
+
+	It("uses a func for the type pointed to over the interface", func() {
+		fs.setTypeMergeFunc(versionedTyp, newMergeFuncStub("versioned"))
+		fs.setTypeMergeFunc(reflect.TypeOf(versionedPtrDoc{}), func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+			return reflect.ValueOf(versionedPtrDoc{Rev: 9}), nil
+		})
+
+		returned, err := fs.getFunc(reflect.ValueOf(&versionedPtrDoc{}))(
+			reflect.ValueOf(&versionedPtrDoc{}), reflect.ValueOf(&versionedPtrDoc{}), NewOptions(WithPointerPolicy(PointerAllocate)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(returned.Interface()).To(Equal(&versionedPtrDoc{Rev: 9}))
+	})
+
+	It("copies the interfaces when cloned", func() {
+		fs.setTypeMergeFunc(versionedTyp, newMergeFuncStub("versioned"))
+		clone := fs.clone()
+		clone.setTypeMergeFunc(stringerTyp, newMergeFuncStub("stringer"))
+
+		builtin := len(newFuncSelector().ifaceTypes)
+		Expect(fs.ifaceTypes[builtin:]).To(Equal([]reflect.Type{versionedTyp}))
+		Expect(clone.ifaceTypes[builtin:]).To(Equal([]reflect.Type{versionedTyp, stringerTyp}))
+	})
+
+	Context("merging", func() {
+		type Docs struct {
+			Current versionedDoc
+			Pinned  *versionedDoc
+			Tagged  map[string]versioned
+		}
+
+		var opts *Options
+
+		BeforeEach(func() {
+			opts = NewOptions(WithTypeFunc(versionedTyp, mergeVersioned))
+		})
+
+		It("applies a func for an interface to implementing types", func() {
+			target := Docs{
+				Current: versionedDoc{Rev: 2, Body: "target"},
+				Pinned:  &versionedDoc{Rev: 1, Body: "target"},
+				Tagged: map[string]versioned{
+					"a": versionedDoc{Rev: 1, Body: "target"},
+					"b": &versionedPtrDoc{Rev: 5},
+				},
+			}
+			source := Docs{
+				Current: versionedDoc{Rev: 1, Body: "source"},
+				Pinned:  &versionedDoc{Rev: 3, Body: "source"},
+				Tagged: map[string]versioned{
+					"a": versionedDoc{Rev: 3, Body: "source"},
+					"b": &versionedPtrDoc{Rev: 4},
+				},
+			}
+
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Current.Body).To(Equal("target"))
+			Expect(target.Pinned.Body).To(Equal("source"))
+			Expect(target.Tagged["a"]).To(Equal(versionedDoc{Rev: 3, Body: "source"}))
+			Expect(target.Tagged["b"]).To(Equal(&versionedPtrDoc{Rev: 5}))
+		})
+
+		It("merges through pointers with a func for the type pointed to", func() {
+			opts.SetTypeMergeFunc(docTyp, func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+				doc := t.Interface().(versionedDoc)
+				doc.Body += "+" + s.Interface().(versionedDoc).Body
+				return reflect.ValueOf(doc), nil
+			})
+
+			pinned := &versionedDoc{Body: "a"}
+			target := Docs{Pinned: pinned}
+
+			// pointers are replaced by default
+			err := Merge(&target, Docs{Pinned: &versionedDoc{Body: "b"}}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Pinned.Body).To(Equal("b"))
+
+			target = Docs{Pinned: pinned}
+			opts.SetTypePointerPolicy(docTyp, PointerAllocate)
+			err = Merge(&target, Docs{Pinned: &versionedDoc{Body: "b"}}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Pinned.Body).To(Equal("a+b"))
+			Expect(pinned.Body).To(Equal("a"))
+
+			opts.SetTypePointerPolicy(docTyp, PointerFollow)
+			err = Merge(&target, Docs{Pinned: &versionedDoc{Body: "c"}}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Pinned.Body).To(Equal("a+b+c"))
+		})
+	})
+})
+
 func newMergeFuncStub(s string) MergeFunc {
 	return func(reflect.Value, reflect.Value, *Options) (reflect.Value, error) {
 		return reflect.ValueOf(s), nil
@@ -665,6 +838,66 @@ var _ = Describe("mergeSlice", func() {
 	})
 })
 
+var _ = Describe("mergeArray", func() {
+	type RGB [3]uint8
+
+	It("merges element by element", func() {
+		merged, err := mergeArray(reflect.ValueOf(RGB{10, 0, 30}), reflect.ValueOf(RGB{0, 20, 40}), NewOptions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged.Interface()).To(Equal(RGB{10, 20, 40}))
+	})
+
+	It("keeps non zero target elements without overwrite", func() {
+		opts := NewOptions()
+		opts.Overwrite = false
+
+		merged, err := mergeArray(reflect.ValueOf(RGB{10, 0, 30}), reflect.ValueOf(RGB{0, 20, 40}), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged.Interface()).To(Equal(RGB{10, 20, 30}))
+	})
+
+	It("merges nested elements", func() {
+		target := [2]map[string]int{{"a": 1}, nil}
+		source := [2]map[string]int{{"b": 2}, {"c": 3}}
+
+		merged, err := mergeArray(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged.Interface()).To(Equal([2]map[string]int{{"a": 1, "b": 2}, {"c": 3}}))
+	})
+
+	It("is selected by merge", func() {
+		weights := [2]float64{0.5, 0}
+		err := Merge(&weights, [2]float64{0, 0.25}, NewOptions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(weights).To(Equal([2]float64{0.5, 0.25}))
+	})
+
+	It("can be replaced with SetKindMergeFunc", func() {
+		opts := NewOptions()
+		opts.SetKindMergeFunc(reflect.Array, defaultMergeFunc)
+
+		weights := [2]float64{0.5, 0.5}
+		err := Merge(&weights, [2]float64{0, 0.25}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(weights).To(Equal([2]float64{0, 0.25}))
+	})
+
+	It("reports the failing index", func() {
+		target := [2]interface{}{1, "a"}
+		source := [2]interface{}{2, 3}
+
+		_, err := mergeArray(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("index 1: Types do not match: string, int"))
+	})
+
+	It("errors on different types", func() {
+		_, err := mergeArray(reflect.ValueOf([2]int{}), reflect.ValueOf([3]int{}), NewOptions())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("arrays must have same type"))
+	})
+})
+
 var _ = Describe("mergeStruct", func() {
 	type Foo struct {
 		Name    string
@@ -1054,6 +1287,191 @@ var _ = Describe("mergeStruct", func() {
 		})
 	})
 
+	Context("embedded structs", func() {
+		type Inner struct {
+			Name string
+			Tags []string
+		}
+
+		type inner struct {
+			Name string
+			Tags []string
+		}
+
+		type hidden struct {
+			Name   string
+			secret string
+		}
+
+		It("merges exported embedded structs", func() {
+			type Outer struct {
+				Inner
+				Size int
+			}
+
+			target := Outer{Inner: Inner{Name: "target", Tags: []string{"a"}}, Size: 1}
+			source := Outer{Inner: Inner{Name: "source", Tags: []string{"b"}}}
+
+			merged, err := mergeStruct(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(merged.Interface()).To(Equal(Outer{Inner: Inner{Name: "source", Tags: []string{"a", "b"}}}))
+		})
+
+		It("merges the promoted fields of unexported embedded structs", func() {
+			type Outer struct {
+				inner
+				Size int
+			}
+
+			target := Outer{inner: inner{Name: "target", Tags: []string{"a"}}, Size: 1}
+			source := Outer{inner: inner{Name: "source", Tags: []string{"b"}}, Size: 2}
+
+			merged, err := mergeStruct(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(merged.Interface()).To(Equal(Outer{inner: inner{Name: "source", Tags: []string{"a", "b"}}, Size: 2}))
+		})
+
+		Context("unexported embedded struct with unexported fields", func() {
+			type Outer struct {
+				hidden
+			}
+
+			var target, source Outer
+
+			BeforeEach(func() {
+				target = Outer{hidden: hidden{Name: "target", secret: "target"}}
+				source = Outer{hidden: hidden{Name: "source", secret: "source"}}
+			})
+
+			It("replaces the whole struct", func() {
+				merged, err := mergeStruct(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(merged.Interface()).To(Equal(source))
+			})
+
+			It("errors with ErrorOnUnexported", func() {
+				opt := NewOptions()
+				opt.ErrorOnUnexported = true
+
+				_, err := mergeStruct(reflect.ValueOf(target), reflect.ValueOf(source), opt)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("struct of type hidden has unexported field: secret"))
+			})
+		})
+
+		Context("pointer embeds", func() {
+			type Outer struct {
+				*Inner
+				Size int
+			}
+
+			It("merges what they point to without modifying the target", func() {
+				target := Outer{Inner: &Inner{Name: "target", Tags: []string{"a"}}}
+				source := Outer{Inner: &Inner{Tags: []string{"b"}}}
+
+				merged, err := mergeStruct(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+				Expect(err).ToNot(HaveOccurred())
+
+				mergedOuter := merged.Interface().(Outer)
+				Expect(*mergedOuter.Inner).To(Equal(Inner{Name: "", Tags: []string{"a", "b"}}))
+				Expect(mergedOuter.Inner).ToNot(BeIdenticalTo(target.Inner))
+				Expect(target.Inner.Name).To(Equal("target"))
+			})
+
+			It("takes the source pointer if the target is nil", func() {
+				target := Outer{}
+				source := Outer{Inner: &Inner{Name: "source"}}
+
+				merged, err := mergeStruct(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(merged.Interface().(Outer).Inner.Name).To(Equal("source"))
+			})
+		})
+	})
+
+	Context("unexported field modes", func() {
+		type Cache struct {
+			mu      sync.Mutex
+			entries map[string]int
+			Name    string
+			Tags    []string
+		}
+
+		var (
+			opt              *Options
+			target, source   *Cache
+			targetV, sourceV reflect.Value
+		)
+
+		BeforeEach(func() {
+			opt = NewOptions()
+			target = &Cache{entries: map[string]int{"a": 1}, Name: "target", Tags: []string{"a"}}
+			source = &Cache{entries: map[string]int{"b": 2}, Name: "source", Tags: []string{"b"}}
+		})
+
+		JustBeforeEach(func() {
+			targetV = reflect.ValueOf(target)
+			sourceV = reflect.ValueOf(source)
+		})
+
+		Context("MergeUnexported", func() {
+			BeforeEach(func() {
+				opt.MergeUnexported = true
+			})
+
+			It("merges unexported fields", func() {
+				merged, err := mergeStruct(targetV, sourceV, opt)
+				Expect(err).ToNot(HaveOccurred())
+
+				mergedCache := merged.Addr().Interface().(*Cache)
+				Expect(mergedCache.entries).To(Equal(map[string]int{"a": 1, "b": 2}))
+				Expect(mergedCache.Name).To(Equal("source"))
+				Expect(mergedCache.Tags).To(Equal([]string{"a", "b"}))
+			})
+
+			It("works through Merge on non-addressable values", func() {
+				type pair struct {
+					left, right string
+				}
+
+				t := pair{left: "l"}
+				err := Merge(&t, pair{right: "r"}, opt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(t).To(Equal(pair{left: "", right: "r"}))
+			})
+
+			It("reports errors on unexported fields", func() {
+				opt.SetTypeMergeFunc(reflect.TypeOf(map[string]int{}), erroringMergeFunc)
+
+				_, err := mergeStruct(targetV, sourceV, opt)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to merge field `Cache.entries`: returns error"))
+			})
+
+			It("is overridden by ErrorOnUnexported", func() {
+				opt.ErrorOnUnexported = true
+
+				_, err := mergeStruct(targetV, sourceV, opt)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("struct of type Cache has unexported field: mu"))
+			})
+		})
+
+		Context("SkipUnexported", func() {
+			It("keeps the target unexported fields and merges the rest", func() {
+				opt.SkipUnexported = true
+
+				merged, err := mergeStruct(targetV, sourceV, opt)
+				Expect(err).ToNot(HaveOccurred())
+
+				mergedCache := merged.Addr().Interface().(*Cache)
+				Expect(mergedCache.entries).To(Equal(map[string]int{"a": 1}))
+				Expect(mergedCache.Name).To(Equal("source"))
+				Expect(mergedCache.Tags).To(Equal([]string{"a", "b"}))
+			})
+		})
+	})
+
 	Context("can not merge interface field containing different types", func() {
 		type Baz struct {
 			Foo interface{}
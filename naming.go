@@ -0,0 +1,212 @@
+package conjungo
+
+import (
+	"reflect"
+	"strings"
+)
+
+// structField is an exported field of a struct, named according to Options.TagName.
+type structField struct {
+	name  string
+	index []int
+	opts  tagOptions
+}
+
+// fieldName returns the name of the struct field according to Options.TagName, along with
+// the options from the tag. A `conjungo` tag always takes precedence over the configured tag.
+// It reports false if the field is excluded with a tag of "-".
+func fieldName(f reflect.StructField, opt *Options) (string, tagOptions, bool) {
+	keys := []string{tagKey}
+	if opt != nil && opt.TagName != "" && opt.TagName != tagKey {
+		keys = append(keys, opt.TagName)
+	}
+
+	var opts tagOptions
+	for _, key := range keys {
+		tag, ok := f.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		if tag == "-" {
+			return "", "", false
+		}
+
+		name, tagOpts := parseTag(tag)
+		if opts == "" {
+			opts = tagOpts
+		}
+
+		if name != "" {
+			return name, opts, true
+		}
+	}
+
+	return f.Name, opts, true
+}
+
+// structFields lists the exported fields of the struct type in order under their names
+// according to the options. The fields of a struct field tagged `inline` or `squash` are
+// listed in place of the field itself, as are those of embedded structs if Options.Squash
+// is set or the embedded type is unexported. Where names collide, the least nested field wins.
+func structFields(t reflect.Type, opt *Options) []structField {
+	var fields []structField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, opts, ok := fieldName(f, opt)
+		if !ok {
+			continue
+		}
+
+		if isFlattened(f, opts, opt) {
+			for _, inner := range structFields(indirectType(f.Type), opt) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fields = append(fields, structField{name: name, index: []int{i}, opts: opts})
+	}
+
+	return dedupeFields(fields)
+}
+
+// isFlattened reports whether the fields of the given struct field should be treated as if
+// they were fields of its parent.
+func isFlattened(f reflect.StructField, opts tagOptions, opt *Options) bool {
+	if indirectType(f.Type).Kind() != reflect.Struct {
+		return false
+	}
+
+	if !f.Anonymous {
+		return f.PkgPath == "" && isSquashTagged(f, opts)
+	}
+
+	if f.PkgPath != "" {
+		// an unexported embedded pointer can not be allocated, so its fields can not be set
+		return f.Type.Kind() == reflect.Struct
+	}
+
+	return (opt != nil && opt.Squash) || isSquashTagged(f, opts)
+}
+
+// isSquashTagged reports whether the field has the `inline` or `squash` option in its tag
+// according to the options, or in any of the other known tags.
+func isSquashTagged(f reflect.StructField, opts tagOptions) bool {
+	if opts.Contains("inline") || opts.Contains("squash") {
+		return true
+	}
+
+	for _, key := range mapKeyTags {
+		_, tagOpts := parseTag(f.Tag.Get(key))
+		if tagOpts.Contains("inline") || tagOpts.Contains("squash") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dedupeFields drops fields whose names are shadowed by a less nested field of the same name.
+func dedupeFields(fields []structField) []structField {
+	byName := map[string]int{}
+	for i, f := range fields {
+		if j, ok := byName[f.name]; !ok || len(f.index) < len(fields[j].index) {
+			byName[f.name] = i
+		}
+	}
+
+	deduped := fields[:0:0]
+	for i, f := range fields {
+		if byName[f.name] == i {
+			deduped = append(deduped, f)
+		}
+	}
+
+	return deduped
+}
+
+// fieldByIndexAlloc returns the nested field of the settable struct v at the given index.
+// Pointers to embedded structs on the way are replaced with pointers to copies, allocating
+// them if nil, so that the field can be set without modifying the original pointee.
+// It reports false if a pointer on the way can not be set.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if !v.CanSet() {
+				return reflect.Value{}, false
+			}
+
+			cp := reflect.New(v.Type().Elem())
+			if !v.IsNil() {
+				cp.Elem().Set(v.Elem())
+			}
+			v.Set(cp)
+			v = cp.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, v.CanSet()
+}
+
+// structFieldsByKey maps each key that may refer to an exported field of the struct type to
+// the index of the field. The name according to the options takes precedence, followed by
+// the names in other known tags, the Go name, and finally the lower cased Go name for case
+// insensitive matching.
+func structFieldsByKey(t reflect.Type, opt *Options) map[string][]int {
+	fields := map[string][]int{}
+
+	add := func(key string, index []int) {
+		if _, ok := fields[key]; !ok && key != "" && key != "-" {
+			fields[key] = index
+		}
+	}
+
+	named := cachedStructFields(t, opt)
+	for _, f := range named {
+		add(f.name, f.index)
+	}
+
+	for _, f := range named {
+		sf := t.FieldByIndex(f.index)
+		for _, tag := range mapKeyTags {
+			name, _ := parseTag(sf.Tag.Get(tag))
+			add(name, f.index)
+		}
+	}
+
+	for _, f := range named {
+		add(t.FieldByIndex(f.index).Name, f.index)
+	}
+
+	for _, f := range named {
+		add(strings.ToLower(t.FieldByIndex(f.index).Name), f.index)
+	}
+
+	return fields
+}
+
+// isOmitted reports whether a field value should be left out when written to a map, which
+// is the case for zero values of fields tagged `omitempty` or `omitzero`.
+func isOmitted(v reflect.Value, opts tagOptions) bool {
+	if !opts.Contains("omitempty") && !opts.Contains("omitzero") {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String, reflect.Array:
+		if v.Len() == 0 {
+			return true
+		}
+	}
+
+	return v.IsZero()
+}
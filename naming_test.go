@@ -0,0 +1,234 @@
+package conjungo
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fieldName", func() {
+	type Tagged struct {
+		Plain    string
+		JSON     string `json:"json_name,omitempty"`
+		Skipped  string `json:"-"`
+		Dash     string `json:"-,"`
+		Override string `json:"json_override" conjungo:"conjungo_override"`
+		OptsOnly string `json:",omitempty"`
+		YAML     string `yaml:"yaml_name"`
+	}
+
+	typ := reflect.TypeOf(Tagged{})
+
+	DescribeTable("names fields",
+		func(field, tagName, expected string, included bool) {
+			f, _ := typ.FieldByName(field)
+			opts := NewOptions()
+			opts.TagName = tagName
+
+			name, _, ok := fieldName(f, opts)
+			Expect(ok).To(Equal(included))
+			Expect(name).To(Equal(expected))
+		},
+		Entry("go name by default", "JSON", "", "JSON", true),
+		Entry("plain field with json", "Plain", "json", "Plain", true),
+		Entry("json tag", "JSON", "json", "json_name", true),
+		Entry("json skip", "Skipped", "json", "", false),
+		Entry("json skip ignored for go names", "Skipped", "", "Skipped", true),
+		Entry("json dash name", "Dash", "json", "-", true),
+		Entry("conjungo tag wins", "Override", "json", "conjungo_override", true),
+		Entry("options only", "OptsOnly", "json", "OptsOnly", true),
+		Entry("yaml tag", "YAML", "yaml", "yaml_name", true),
+		Entry("custom tag missing", "YAML", "toml", "YAML", true),
+	)
+
+	It("returns the tag options", func() {
+		f, _ := typ.FieldByName("OptsOnly")
+		opts := NewOptions()
+		opts.TagName = "json"
+
+		_, tagOpts, _ := fieldName(f, opts)
+		Expect(tagOpts.Contains("omitempty")).To(BeTrue())
+	})
+})
+
+var _ = Describe("TagName", func() {
+	type Meta struct {
+		Owner string `yaml:"owner"`
+	}
+
+	type Service struct {
+		Meta     `yaml:",inline"`
+		Name     string            `yaml:"name"`
+		Replicas int               `yaml:"replicas,omitempty"`
+		Labels   map[string]string `yaml:"labels,omitempty"`
+		Internal string            `yaml:"-"`
+		Bad      badType           `yaml:"bad_field"`
+	}
+
+	var opts *Options
+
+	BeforeEach(func() {
+		opts = NewOptions()
+		opts.TagName = "yaml"
+	})
+
+	It("merges maps keyed by tag name, including inline fields", func() {
+		target := Service{Name: "target", Replicas: 1}
+		source := map[string]interface{}{"name": "source", "owner": "team", "Internal": "x"}
+
+		opts.ErrorOnUnmatched = true
+		err := MergeFields(&target, source, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*UnmatchedFieldsError).Fields).To(Equal([]string{"Internal"}))
+
+		opts.ErrorOnUnmatched = false
+		err = MergeFields(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("source"))
+		Expect(target.Owner).To(Equal("team"))
+		Expect(target.Internal).To(BeEmpty())
+	})
+
+	It("writes maps keyed by tag name, omitting empty values", func() {
+		target := map[string]interface{}{}
+		source := Service{Meta: Meta{Owner: "team"}, Name: "source", Internal: "x"}
+
+		err := MergeFields(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{
+			"owner":     "team",
+			"name":      "source",
+			"bad_field": badType(""),
+		}))
+	})
+
+	It("uses tag names in struct merge errors", func() {
+		opts.SetTypeMergeFunc(reflect.TypeOf(badType("")), erroringMergeFunc)
+
+		err := Merge(&Service{Bad: "a"}, Service{Bad: "b"}, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to merge field `Service.bad_field`: returns error"))
+	})
+
+	It("uses Go names in struct merge errors for fields skipped by tag", func() {
+		type Skipping struct {
+			Hidden badType `yaml:"-"`
+		}
+		opts.SetTypeMergeFunc(reflect.TypeOf(badType("")), erroringMergeFunc)
+
+		err := Merge(&Skipping{Hidden: "a"}, Skipping{Hidden: "b"}, opts)
+		Expect(err).To(MatchError(ContainSubstring("failed to merge field `Skipping.Hidden`: returns error")))
+	})
+
+	It("reports unmatched keys as written", func() {
+		opts.ErrorOnUnmatched = true
+		target := Service{}
+		source := map[string]interface{}{"Meta": map[string]interface{}{"owner": "x"}, "replicaz": 2}
+
+		err := MergeFields(&target, source, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*UnmatchedFieldsError).Fields).To(Equal([]string{"Meta", "replicaz"}))
+	})
+})
+
+var _ = Describe("Squash", func() {
+	type Base struct {
+		ID   string
+		Kind string
+	}
+
+	type base struct {
+		Owner string
+	}
+
+	type Resource struct {
+		Base
+		*base
+		Name string
+		Kind string
+	}
+
+	type Tagged struct {
+		Base `mapstructure:",squash"`
+		Name string
+	}
+
+	var opts *Options
+
+	BeforeEach(func() {
+		opts = NewOptions()
+		opts.ErrorOnUnmatched = true
+	})
+
+	It("treats embedded structs as named fields by default", func() {
+		target := Resource{}
+		source := map[string]interface{}{"Base": map[string]interface{}{"ID": "1"}, "Name": "n"}
+
+		err := MergeFields(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.ID).To(Equal("1"))
+		Expect(target.Name).To(Equal("n"))
+	})
+
+	It("squashes embedded structs when enabled", func() {
+		opts.Squash = true
+		target := Resource{Base: Base{Kind: "base"}, Kind: "outer"}
+		source := map[string]interface{}{"ID": "1", "Kind": "new"}
+
+		err := MergeFields(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.ID).To(Equal("1"))
+		Expect(target.Kind).To(Equal("new"))
+		Expect(target.Base.Kind).To(Equal("base"))
+	})
+
+	It("squashes embedded structs tagged squash", func() {
+		target := Tagged{}
+		source := map[string]interface{}{"ID": "1", "Name": "n"}
+
+		err := MergeFields(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(Tagged{Base: Base{ID: "1"}, Name: "n"}))
+	})
+
+	It("writes squashed fields at the parent level", func() {
+		opts.Squash = true
+		target := map[string]interface{}{}
+
+		err := MergeFields(&target, Resource{Base: Base{ID: "1", Kind: "base"}, Kind: "outer"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{"ID": "1", "Kind": "outer", "Name": ""}))
+	})
+
+	It("can not set fields promoted through unexported embedded pointers", func() {
+		target := Resource{}
+
+		err := MergeFields(&target, map[string]interface{}{"Owner": "x"}, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*UnmatchedFieldsError).Fields).To(Equal([]string{"Owner"}))
+	})
+
+	It("allocates embedded pointers without modifying the target", func() {
+		type Wrapper struct {
+			*Base
+		}
+
+		original := &Base{ID: "1"}
+		target := Wrapper{Base: original}
+
+		opts.Squash = true
+		err := MergeFields(&target, map[string]interface{}{"Kind": "k"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*target.Base).To(Equal(Base{ID: "1", Kind: "k"}))
+		Expect(original.Kind).To(BeEmpty())
+
+		empty := Wrapper{}
+		err = MergeFields(&empty, map[string]interface{}{"ID": "2"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(empty.Base).To(Equal(&Base{ID: "2"}))
+	})
+})
+
+type badType string
@@ -0,0 +1,194 @@
+package conjungo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// OverflowError is returned by the numeric merge funcs when a result does not fit in the
+// type of the values merged.
+type OverflowError struct {
+	// Op is the strategy that overflowed, such as "sum"
+	Op string
+
+	Type           reflect.Type
+	Target, Source interface{}
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("%s of %v and %v overflows %v", e.Op, e.Target, e.Source, e.Type)
+}
+
+// MergeSum is a MergeFunc which adds two numbers of any int, uint, float or complex kind,
+// including types such as time.Duration. It returns an OverflowError if the sum does not
+// fit in their type.
+func MergeSum(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	sum := reflect.New(t.Type()).Elem()
+
+	switch k := t.Kind(); {
+	case isIntKind(k):
+		a, b := t.Int(), s.Int()
+		x := a + b
+		if (b > 0 && x < a) || (b < 0 && x > a) || sum.OverflowInt(x) {
+			return reflect.Value{}, overflow(StrategySum, t, s)
+		}
+		sum.SetInt(x)
+
+	case isUintKind(k):
+		a, b := t.Uint(), s.Uint()
+		x := a + b
+		if x < a || sum.OverflowUint(x) {
+			return reflect.Value{}, overflow(StrategySum, t, s)
+		}
+		sum.SetUint(x)
+
+	case isFloatKind(k):
+		a, b := t.Float(), s.Float()
+		x := a + b
+		if (math.IsInf(x, 0) && !math.IsInf(a, 0) && !math.IsInf(b, 0)) || sum.OverflowFloat(x) {
+			return reflect.Value{}, overflow(StrategySum, t, s)
+		}
+		sum.SetFloat(x)
+
+	case isComplexKind(k):
+		a, b := t.Complex(), s.Complex()
+		x := a + b
+		if complexOverflows(x, a, b) || sum.OverflowComplex(x) {
+			return reflect.Value{}, overflow(StrategySum, t, s)
+		}
+		sum.SetComplex(x)
+
+	default:
+		return reflect.Value{}, fmt.Errorf("strategy %s requires numbers, got %v", StrategySum, t.Type())
+	}
+
+	return sum, nil
+}
+
+// MergeAverage is a MergeFunc which returns the mean of two numbers of any int, uint, float
+// or complex kind. Integers are rounded towards zero. The mean is computed without
+// overflowing. Note that merging several sources in turn weighs the later ones more.
+func MergeAverage(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	avg := reflect.New(t.Type()).Elem()
+
+	switch k := t.Kind(); {
+	case isIntKind(k):
+		a, b := t.Int(), s.Int()
+		x := a/2 + b/2
+		// add back the halves lost to truncation, rounding the result towards zero
+		switch r := a%2 + b%2; {
+		case r == 2 || r == -2:
+			x += r / 2
+		case r == 1 && x < 0:
+			x++
+		case r == -1 && x > 0:
+			x--
+		}
+		avg.SetInt(x)
+
+	case isUintKind(k):
+		a, b := t.Uint(), s.Uint()
+		avg.SetUint(a/2 + b/2 + (a%2+b%2)/2)
+
+	case isFloatKind(k):
+		avg.SetFloat(t.Float()/2 + s.Float()/2)
+
+	case isComplexKind(k):
+		avg.SetComplex(t.Complex()/2 + s.Complex()/2)
+
+	default:
+		return reflect.Value{}, fmt.Errorf("strategy %s requires numbers, got %v", StrategyAverage, t.Type())
+	}
+
+	return avg, nil
+}
+
+// MergeMax is a MergeFunc which keeps the greater of two numbers of any int, uint or float
+// kind, or of two strings. NaN is never kept over another value.
+func MergeMax(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if isNaN(t) {
+		return s, nil
+	}
+	if isNaN(s) {
+		return t, nil
+	}
+
+	less, err := lessValue(t, s, StrategyMax)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if less {
+		return s, nil
+	}
+	return t, nil
+}
+
+// MergeMin is a MergeFunc which keeps the lesser of two numbers of any int, uint or float
+// kind, or of two strings. NaN is never kept over another value.
+func MergeMin(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if isNaN(t) {
+		return s, nil
+	}
+	if isNaN(s) {
+		return t, nil
+	}
+
+	less, err := lessValue(s, t, StrategyMin)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if less {
+		return s, nil
+	}
+	return t, nil
+}
+
+// lessValue reports whether a is less than b, which must be numbers or strings of the
+// same type.
+func lessValue(a, b reflect.Value, name string) (bool, error) {
+	switch k := a.Kind(); {
+	case isIntKind(k):
+		return a.Int() < b.Int(), nil
+	case isUintKind(k):
+		return a.Uint() < b.Uint(), nil
+	case isFloatKind(k):
+		return a.Float() < b.Float(), nil
+	case k == reflect.String:
+		return a.String() < b.String(), nil
+	}
+
+	return false, fmt.Errorf("strategy %s requires numbers or strings, got %v", name, a.Type())
+}
+
+func overflow(op string, t, s reflect.Value) error {
+	return &OverflowError{Op: op, Type: t.Type(), Target: t.Interface(), Source: s.Interface()}
+}
+
+// complexOverflows reports whether either part of x is infinite where those of a and b
+// are not.
+func complexOverflows(x, a, b complex128) bool {
+	parts := func(c complex128) (bool, bool) {
+		return math.IsInf(real(c), 0), math.IsInf(imag(c), 0)
+	}
+
+	xr, xi := parts(x)
+	ar, ai := parts(a)
+	br, bi := parts(b)
+
+	return (xr && !ar && !br) || (xi && !ai && !bi)
+}
+
+func isNaN(v reflect.Value) bool {
+	return isFloatKind(v.Kind()) && math.IsNaN(v.Float())
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isComplexKind(k reflect.Kind) bool {
+	return k == reflect.Complex64 || k == reflect.Complex128
+}
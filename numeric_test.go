@@ -0,0 +1,143 @@
+package conjungo
+
+import (
+	"math"
+	"reflect"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("numeric merge funcs", func() {
+	type count uint16
+
+	DescribeTable("MergeSum",
+		func(target, source, expected interface{}) {
+			Expect(applyMergeFunc(MergeSum, target, source)).To(Equal(expected))
+		},
+		Entry("int", 1, 2, 3),
+		Entry("negative int8", int8(-100), int8(-28), int8(-128)),
+		Entry("int64", int64(math.MaxInt64-1), int64(1), int64(math.MaxInt64)),
+		Entry("uint8", uint8(200), uint8(55), uint8(255)),
+		Entry("named uint16", count(1), count(2), count(3)),
+		Entry("float32", float32(1.5), float32(2), float32(3.5)),
+		Entry("float64", 0.25, 0.5, 0.75),
+		Entry("complex64", complex64(1+2i), complex64(3+4i), complex64(4+6i)),
+		Entry("complex128", 1+2i, -1-2i, complex128(0)),
+		Entry("duration", time.Second, time.Millisecond, 1001*time.Millisecond),
+	)
+
+	DescribeTable("MergeSum overflow",
+		func(target, source interface{}) {
+			_, err := applyMergeFunc(MergeSum, target, source)
+			Expect(err).To(BeAssignableToTypeOf(&OverflowError{}))
+		},
+		Entry("int8", int8(100), int8(28)),
+		Entry("negative int8", int8(-100), int8(-29)),
+		Entry("int64", int64(math.MaxInt64), int64(1)),
+		Entry("negative int64", int64(math.MinInt64), int64(-1)),
+		Entry("uint16", uint16(math.MaxUint16), uint16(1)),
+		Entry("uint64", uint64(math.MaxUint64), uint64(2)),
+		Entry("float32", float32(math.MaxFloat32), float32(math.MaxFloat32)),
+		Entry("float64", math.MaxFloat64, math.MaxFloat64),
+		Entry("complex64", complex(float32(1), float32(math.MaxFloat32)), complex(float32(1), float32(math.MaxFloat32))),
+		Entry("complex128", complex(math.MaxFloat64, 0), complex(math.MaxFloat64, 0)),
+		Entry("duration", time.Duration(math.MaxInt64), time.Nanosecond),
+	)
+
+	It("describes overflows", func() {
+		_, err := applyMergeFunc(MergeSum, int8(100), int8(28))
+		Expect(err).To(MatchError("sum of 100 and 28 overflows int8"))
+	})
+
+	It("does not report infinite values as overflows", func() {
+		Expect(applyMergeFunc(MergeSum, math.Inf(1), 1.0)).To(Equal(math.Inf(1)))
+	})
+
+	DescribeTable("MergeAverage",
+		func(target, source, expected interface{}) {
+			Expect(applyMergeFunc(MergeAverage, target, source)).To(Equal(expected))
+		},
+		Entry("int", 1, 3, 2),
+		Entry("int rounds towards zero", 2, 3, 2),
+		Entry("negative int rounds towards zero", -2, -3, -2),
+		Entry("mixed signs", -3, 2, 0),
+		Entry("mixed signs rounding", -1, 4, 1),
+		Entry("mixed odd signs", 3, -6, -1),
+		Entry("int8 without overflow", int8(127), int8(125), int8(126)),
+		Entry("negative int8 without overflow", int8(-128), int8(-127), int8(-127)),
+		Entry("odd int64 extremes", int64(math.MaxInt64), int64(math.MaxInt64), int64(math.MaxInt64)),
+		Entry("uint8 without overflow", uint8(255), uint8(253), uint8(254)),
+		Entry("odd uints", uint(1), uint(1), uint(1)),
+		Entry("float64", 1.0, 2.0, 1.5),
+		Entry("float64 without overflow", math.MaxFloat64, math.MaxFloat64, math.MaxFloat64),
+		Entry("complex128", 1+1i, 3+3i, 2+2i),
+		Entry("duration", time.Second, 2*time.Second, 1500*time.Millisecond),
+	)
+
+	DescribeTable("MergeMax and MergeMin",
+		func(target, source, max, min interface{}) {
+			Expect(applyMergeFunc(MergeMax, target, source)).To(Equal(max))
+			Expect(applyMergeFunc(MergeMin, target, source)).To(Equal(min))
+		},
+		Entry("int", 1, 2, 2, 1),
+		Entry("int16", int16(-5), int16(3), int16(3), int16(-5)),
+		Entry("uint64", uint64(math.MaxUint64), uint64(0), uint64(math.MaxUint64), uint64(0)),
+		Entry("float32", float32(-1), float32(-2), float32(-1), float32(-2)),
+		Entry("duration", time.Minute, time.Second, time.Minute, time.Second),
+		Entry("string", "b", "a", "b", "a"),
+	)
+
+	It("never keeps NaN over another value", func() {
+		nan := math.NaN()
+		Expect(applyMergeFunc(MergeMax, nan, 1.0)).To(Equal(1.0))
+		Expect(applyMergeFunc(MergeMax, 1.0, nan)).To(Equal(1.0))
+		Expect(applyMergeFunc(MergeMin, nan, 1.0)).To(Equal(1.0))
+		Expect(applyMergeFunc(MergeMin, 1.0, nan)).To(Equal(1.0))
+	})
+
+	DescribeTable("unsupported kinds",
+		func(mf MergeFunc, value interface{}, msg string) {
+			_, err := applyMergeFunc(mf, value, value)
+			Expect(err).To(MatchError(msg))
+		},
+		Entry("sum of strings", MergeSum, "a", "strategy sum requires numbers, got string"),
+		Entry("average of bools", MergeAverage, true, "strategy average requires numbers, got bool"),
+		Entry("max of complex", MergeMax, 1i, "strategy max requires numbers or strings, got complex128"),
+		Entry("min of slices", MergeMin, []int{}, "strategy min requires numbers or strings, got []int"),
+	)
+
+	It("can be set for kinds", func() {
+		opts := NewOptions()
+		opts.SetKindMergeFunc(reflect.Int, MergeSum)
+		opts.SetKindMergeFunc(reflect.Float64, MergeMax)
+
+		type snapshot struct {
+			Requests int
+			Peak     float64
+		}
+
+		target := snapshot{Requests: 10, Peak: 0.5}
+		err := Merge(&target, snapshot{Requests: 5, Peak: 0.25}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(snapshot{Requests: 15, Peak: 0.5}))
+
+		err = Merge(&target, snapshot{Requests: math.MaxInt}, opts)
+		Expect(err).To(MatchError(ContainSubstring("sum of 15 and 9223372036854775807 overflows int")))
+		Expect(target.Requests).To(Equal(15))
+	})
+
+	It("can be used in tags", func() {
+		type quota struct {
+			CPU     float64       `conjungo:",strategy=average"`
+			Timeout time.Duration `conjungo:",strategy=max"`
+		}
+
+		target := quota{CPU: 1, Timeout: time.Second}
+		err := Merge(&target, quota{CPU: 2, Timeout: time.Minute}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(quota{CPU: 1.5, Timeout: time.Minute}))
+	})
+})
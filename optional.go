@@ -0,0 +1,186 @@
+package conjungo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Optional holds a value that may be unset, explicitly null, or set, such as a field of a
+// partial update. The zero Optional is unset.
+//
+// When merged, an unset source keeps the target, and a null or set source replaces an unset
+// target, or any target according to Overwrite. Where both are set, their values are merged.
+//
+// Optional marshals to and from JSON and YAML, where a missing field is unset and a null
+// field is null. Tag fields with `omitzero` in JSON or `omitempty` in YAML so that unset
+// values are left out. Note that gopkg.in/yaml.v3 does not unmarshal null values into
+// types, so that a null YAML field decodes as unset.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+type optionalState uint8
+
+const (
+	optionalUnset optionalState = iota
+	optionalNull
+	optionalSet
+)
+
+// Some returns an Optional set to the value.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, state: optionalSet}
+}
+
+// Null returns an Optional which is explicitly null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// Get returns the value and whether it is set.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.state == optionalSet
+}
+
+// OrElse returns the value if it is set, or def otherwise.
+func (o Optional[T]) OrElse(def T) T {
+	if o.state == optionalSet {
+		return o.value
+	}
+	return def
+}
+
+// IsSet reports whether the Optional is set to a value.
+func (o Optional[T]) IsSet() bool {
+	return o.state == optionalSet
+}
+
+// IsNull reports whether the Optional is explicitly null.
+func (o Optional[T]) IsNull() bool {
+	return o.state == optionalNull
+}
+
+// IsZero reports whether the Optional is unset.
+func (o Optional[T]) IsZero() bool {
+	return o.state == optionalUnset
+}
+
+func (o Optional[T]) String() string {
+	switch o.state {
+	case optionalSet:
+		return fmt.Sprint(o.value)
+	case optionalNull:
+		return "null"
+	}
+	return "unset"
+}
+
+// MarshalJSON encodes the value if it is set, and null otherwise.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.state != optionalSet {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON sets the Optional to the decoded value, or to null.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*o = Null[T]()
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*o = Some(v)
+	return nil
+}
+
+// MarshalYAML encodes the value if it is set, and null otherwise.
+func (o Optional[T]) MarshalYAML() (interface{}, error) {
+	if o.state != optionalSet {
+		return nil, nil
+	}
+	return o.value, nil
+}
+
+// UnmarshalYAML sets the Optional to the decoded value.
+func (o *Optional[T]) UnmarshalYAML(node *yaml.Node) error {
+	var v T
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+
+	*o = Some(v)
+	return nil
+}
+
+// optional is implemented by all Optional types, so that a single merge func is found
+// for them.
+type optional interface {
+	optionalState() optionalState
+	optionalValue() reflect.Value
+	optionalType() reflect.Type
+	optionalWith(v reflect.Value) reflect.Value
+}
+
+var optionalIface = reflect.TypeOf((*optional)(nil)).Elem()
+
+func (o Optional[T]) optionalState() optionalState {
+	return o.state
+}
+
+func (o Optional[T]) optionalValue() reflect.Value {
+	return reflect.ValueOf(&o.value).Elem()
+}
+
+func (o Optional[T]) optionalType() reflect.Type {
+	return reflect.TypeOf(o)
+}
+
+// optionalWith returns an Optional set to the value, which must be assignable to T.
+func (o Optional[T]) optionalWith(v reflect.Value) reflect.Value {
+	var val T
+	if v.IsValid() {
+		reflect.ValueOf(&val).Elem().Set(v)
+	}
+	return reflect.ValueOf(Some(val))
+}
+
+// mergeOptional merges two Optional values as described for Optional.
+func mergeOptional(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		return mergePointeeFunc(mergeOptional)(t, s, o)
+	}
+
+	optT, ok := t.Interface().(optional)
+	if !ok || optT.optionalType() != t.Type() {
+		// a type embedding an Optional
+		return mergeDeep(t, s, o)
+	}
+	optS := s.Interface().(optional)
+
+	switch {
+	case optS.optionalState() == optionalUnset:
+		return t, nil
+	case optT.optionalState() == optionalUnset:
+		return s, nil
+	case optT.optionalState() == optionalNull || optS.optionalState() == optionalNull:
+		return defaultMergeFunc(t, s, o)
+	}
+
+	merged, err := merge(optT.optionalValue(), optS.optionalValue(), o)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return optT.optionalWith(merged), nil
+}
@@ -0,0 +1,166 @@
+package conjungo
+
+import (
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+type optionalUpdate struct {
+	Name   Optional[string]            `json:"name,omitzero" yaml:"name,omitempty"`
+	Age    Optional[int]               `json:"age,omitzero" yaml:"age,omitempty"`
+	Tags   Optional[[]string]          `json:"tags,omitzero" yaml:"tags,omitempty"`
+	Labels Optional[map[string]string] `json:"labels,omitzero" yaml:"labels,omitempty"`
+}
+
+var _ = Describe("Optional", func() {
+	It("has three states", func() {
+		var unset Optional[int]
+		Expect(unset.IsZero()).To(BeTrue())
+		Expect(unset.IsNull()).To(BeFalse())
+		Expect(unset.IsSet()).To(BeFalse())
+		Expect(unset.OrElse(3)).To(Equal(3))
+		Expect(unset.String()).To(Equal("unset"))
+
+		null := Null[int]()
+		Expect(null.IsZero()).To(BeFalse())
+		Expect(null.IsNull()).To(BeTrue())
+		Expect(null.OrElse(3)).To(Equal(3))
+		Expect(null.String()).To(Equal("null"))
+
+		some := Some(0)
+		v, ok := some.Get()
+		Expect(v).To(Equal(0))
+		Expect(ok).To(BeTrue())
+		Expect(some.IsZero()).To(BeFalse())
+		Expect(some.OrElse(3)).To(Equal(0))
+		Expect(some.String()).To(Equal("0"))
+	})
+
+	DescribeTable("Merge",
+		func(target, source, expected Optional[string], overwrite bool) {
+			err := Merge(&target, source, NewOptions(WithOverwrite(overwrite)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(expected))
+		},
+		Entry("unset source", Some("a"), Optional[string]{}, Some("a"), true),
+		Entry("unset source onto null", Null[string](), Optional[string]{}, Null[string](), true),
+		Entry("null source", Some("a"), Null[string](), Null[string](), true),
+		Entry("null source without overwrite", Some("a"), Null[string](), Some("a"), false),
+		Entry("set source", Some("a"), Some("b"), Some("b"), true),
+		Entry("set source without overwrite", Some("a"), Some("b"), Some("a"), false),
+		Entry("set source onto unset", Optional[string]{}, Some("b"), Some("b"), false),
+		Entry("null source onto unset", Optional[string]{}, Null[string](), Null[string](), false),
+		Entry("set source onto null", Null[string](), Some("b"), Some("b"), true),
+		Entry("zero source", Some("a"), Some(""), Some(""), true),
+	)
+
+	It("merges set values", func() {
+		target := optionalUpdate{
+			Tags:   Some([]string{"a"}),
+			Labels: Some(map[string]string{"a": "1"}),
+		}
+		source := optionalUpdate{
+			Tags:   Some([]string{"b"}),
+			Labels: Some(map[string]string{"b": "2"}),
+		}
+
+		err := Merge(&target, source, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Tags).To(Equal(Some([]string{"a", "b"})))
+		Expect(target.Labels).To(Equal(Some(map[string]string{"a": "1", "b": "2"})))
+	})
+
+	It("applies partial updates decoded from JSON", func() {
+		target := optionalUpdate{Name: Some("a"), Age: Some(30), Tags: Some([]string{"x"})}
+
+		var patch optionalUpdate
+		err := json.Unmarshal([]byte(`{"age": 31, "tags": null}`), &patch)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patch.Name.IsZero()).To(BeTrue())
+		Expect(patch.Tags.IsNull()).To(BeTrue())
+
+		err = Merge(&target, patch, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(optionalUpdate{Name: Some("a"), Age: Some(31), Tags: Null[[]string]()}))
+	})
+
+	It("marshals JSON", func() {
+		data, err := json.Marshal(optionalUpdate{Name: Some("a"), Age: Null[int]()})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"name":"a","age":null}`))
+
+		var decoded optionalUpdate
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(optionalUpdate{Name: Some("a"), Age: Null[int]()}))
+
+		Expect(json.Unmarshal([]byte(`{"age": "x"}`), &decoded)).ToNot(Succeed())
+	})
+
+	It("marshals YAML", func() {
+		data, err := yaml.Marshal(optionalUpdate{Name: Some("a"), Age: Null[int](), Tags: Some([]string{"x"})})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("name: a\nage: null\ntags:\n    - x\n"))
+
+		var decoded optionalUpdate
+		Expect(yaml.Unmarshal([]byte("name: a\nage:\nlabels: {b: \"2\"}\n"), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(optionalUpdate{
+			Name: Some("a"), Labels: Some(map[string]string{"b": "2"}),
+		}))
+
+		Expect(yaml.Unmarshal([]byte("age: [1]"), &decoded)).ToNot(Succeed())
+	})
+
+	It("merges pointers to optionals", func() {
+		type holder struct {
+			Name *Optional[string]
+		}
+
+		name := Some("a")
+		opts := NewOptions(WithPointerPolicy(PointerFollow))
+		target := holder{Name: &name}
+
+		unset := Optional[string]{}
+		err := Merge(&target, holder{Name: &unset}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(BeIdenticalTo(&name))
+		Expect(name).To(Equal(Some("a")))
+
+		other := Some("b")
+		err = Merge(&target, holder{Name: &other}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal(Some("b")))
+	})
+
+	It("merges optional interfaces", func() {
+		target := Some[interface{}](map[string]interface{}{"a": 1})
+		err := Merge(&target, Some[interface{}](map[string]interface{}{"b": 2}), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(Some[interface{}](map[string]interface{}{"a": 1, "b": 2})))
+	})
+
+	It("does not apply to types embedding an Optional", func() {
+		type wrapper struct {
+			Optional[int]
+			Name string
+		}
+
+		target := wrapper{Optional: Some(1), Name: "a"}
+		err := Merge(&target, wrapper{Name: "b"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(wrapper{Optional: Some(1), Name: "b"}))
+	})
+
+	It("can be overridden for a type", func() {
+		opts := NewOptions(WithTypeFunc(reflect.TypeOf(Optional[int]{}), mergeKeep))
+
+		target := optionalUpdate{Name: Some("a"), Age: Some(1)}
+		err := Merge(&target, optionalUpdate{Name: Some("b"), Age: Some(2)}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(optionalUpdate{Name: Some("b"), Age: Some(1)}))
+	})
+})
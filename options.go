@@ -0,0 +1,122 @@
+package conjungo
+
+import "reflect"
+
+// An Option modifies Options as they are built by NewOptions. Any func(*Options) can be
+// used as an Option, for settings that have no helper below:
+//
+//	opts := conjungo.NewOptions(
+//		conjungo.WithOverwrite(false),
+//		conjungo.WithTypeFunc(reflect.TypeOf(Foo{}), mergeFoo),
+//		func(o *conjungo.Options) { o.TagName = "json" },
+//	).Freeze()
+type Option func(*Options)
+
+// WithOverwrite sets Options.Overwrite.
+func WithOverwrite(overwrite bool) Option {
+	return func(o *Options) {
+		o.Overwrite = overwrite
+	}
+}
+
+// WithTypeFunc sets the merge func for a type. See Options.SetTypeMergeFunc.
+func WithTypeFunc(t reflect.Type, mf MergeFunc) Option {
+	return func(o *Options) {
+		o.SetTypeMergeFunc(t, mf)
+	}
+}
+
+// WithKindFunc sets the merge func for a kind. See Options.SetKindMergeFunc.
+func WithKindFunc(k reflect.Kind, mf MergeFunc) Option {
+	return func(o *Options) {
+		o.SetKindMergeFunc(k, mf)
+	}
+}
+
+// WithDefaultFunc sets the default merge func. See Options.SetDefaultMergeFunc.
+func WithDefaultFunc(mf MergeFunc) Option {
+	return func(o *Options) {
+		o.SetDefaultMergeFunc(mf)
+	}
+}
+
+// WithPointerPolicy sets Options.PointerPolicy.
+func WithPointerPolicy(p PointerPolicy) Option {
+	return func(o *Options) {
+		o.PointerPolicy = p
+	}
+}
+
+// WithTypePointerPolicy sets the pointer policy for a type. See Options.SetTypePointerPolicy.
+func WithTypePointerPolicy(t reflect.Type, p PointerPolicy) Option {
+	return func(o *Options) {
+		o.SetTypePointerPolicy(t, p)
+	}
+}
+
+// Clone returns a copy of the options that can be modified without affecting the
+// original, even if the original is frozen. The copy is not frozen.
+func (o *Options) Clone() *Options {
+	cp := *o
+	cp.state = nil
+
+	if o.mergeFuncs != nil {
+		cp.mergeFuncs = o.mergeFuncs.clone()
+	}
+
+	return &cp
+}
+
+// Freeze prevents the merge funcs and pointer policies of the options from being changed,
+// and returns the options. Calling a setter such as SetTypeMergeFunc on frozen options
+// panics. Frozen options can be shared by concurrent merges, as long as their fields are
+// not modified either. Use Clone to get a copy that can be modified.
+//
+// The merge funcs of options that are not frozen can also be changed while merges are
+// running, though a merge in progress may use either the old or new funcs.
+func (o *Options) Freeze() *Options {
+	if o.mergeFuncs != nil {
+		o.mergeFuncs.freeze()
+	}
+
+	return o
+}
+
+// Frozen reports whether the options are frozen. See Freeze.
+func (o *Options) Frozen() bool {
+	if o.mergeFuncs == nil {
+		return false
+	}
+
+	o.mergeFuncs.mu.RLock()
+	defer o.mergeFuncs.mu.RUnlock()
+
+	return o.mergeFuncs.frozen
+}
+
+// RequiresReflection reports whether the options only take effect when each value is merged
+// by conjungo, as they set hooks, a policy or limits. Merge funcs which merge the fields of
+// a struct themselves, such as those generated by conjungo-gen, fall back on MergeStruct
+// if so.
+func (o *Options) RequiresReflection() bool {
+	return o.BeforeMerge != nil || o.AfterMerge != nil || o.policy != nil ||
+		o.MaxDepth > 0 || o.MaxNodes > 0 || o.MaxSliceLen > 0 || o.MaxMapKeys > 0
+}
+
+// UsesDefaultMergeFuncs reports whether values of each of the types are merged with the
+// merge func NewOptions sets for them, rather than one set for their type, an interface
+// they implement, their kind or as the default. Merge funcs generated by conjungo-gen only
+// merge fields of these types themselves if so.
+func (o *Options) UsesDefaultMergeFuncs(types ...reflect.Type) bool {
+	if o.mergeFuncs == nil {
+		return true
+	}
+
+	for _, t := range types {
+		if !sameFunc(o.mergeFuncs.getTypeFunc(t), builtinFuncs.getTypeFunc(t)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,227 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewOptions with Option funcs", func() {
+	It("applies the options in order", func() {
+		opts := NewOptions(
+			WithOverwrite(false),
+			WithTypeFunc(reflect.TypeOf(""), newMergeFuncStub("type")),
+			WithKindFunc(reflect.Int, newMergeFuncStub("kind")),
+			WithDefaultFunc(newMergeFuncStub("default")),
+			WithPointerPolicy(PointerFollow),
+			WithTypePointerPolicy(reflect.TypeOf(0), PointerAllocate),
+			func(o *Options) { o.TagName = "json" },
+			WithOverwrite(true),
+		)
+
+		Expect(opts.Overwrite).To(BeTrue())
+		Expect(opts.TagName).To(Equal("json"))
+		Expect(opts.PointerPolicy).To(Equal(PointerFollow))
+		Expect(opts.pointerPolicy(reflect.TypeOf(new(int)))).To(Equal(PointerAllocate))
+
+		for value, expected := range map[interface{}]string{"a": "type", 1: "kind", true: "default"} {
+			returned, _ := opts.mergeFuncs.getFunc(reflect.ValueOf(value))(reflect.Value{}, reflect.Value{}, opts)
+			Expect(returned.Interface()).To(Equal(expected))
+		}
+	})
+
+	It("keeps the defaults without options", func() {
+		opts := NewOptions()
+		Expect(opts.Overwrite).To(BeTrue())
+		Expect(opts.Frozen()).To(BeFalse())
+	})
+})
+
+var _ = Describe("Options.Clone", func() {
+	It("copies the options", func() {
+		opts := NewOptions(WithOverwrite(false), WithTypeFunc(reflect.TypeOf(""), erroringMergeFunc))
+		opts.TagName = "yaml"
+
+		clone := opts.Clone()
+		Expect(clone.Overwrite).To(BeFalse())
+		Expect(clone.TagName).To(Equal("yaml"))
+		Expect(clone.mergeFuncs).ToNot(BeIdenticalTo(opts.mergeFuncs))
+		Expect(clone.mergeFuncs.typeFuncs).To(HaveKey(reflect.TypeOf("")))
+	})
+
+	It("is modified independently of the original", func() {
+		opts := NewOptions()
+		clone := opts.Clone()
+		clone.SetTypeMergeFunc(reflect.TypeOf(""), erroringMergeFunc)
+		clone.SetTypePointerPolicy(reflect.TypeOf(0), PointerFollow)
+
+		target := "a"
+		Expect(Merge(&target, "b", opts)).To(Succeed())
+		Expect(target).To(Equal("b"))
+		Expect(Merge(&target, "c", clone)).ToNot(Succeed())
+
+		Expect(opts.mergeFuncs.typeFuncs).ToNot(HaveKey(reflect.TypeOf("")))
+		Expect(opts.mergeFuncs.ptrPolicies).ToNot(HaveKey(reflect.TypeOf(0)))
+	})
+
+	It("can modify frozen options", func() {
+		opts := NewOptions().Freeze()
+
+		clone := opts.Clone()
+		Expect(clone.Frozen()).To(BeFalse())
+		Expect(func() { clone.SetDefaultMergeFunc(erroringMergeFunc) }).ToNot(Panic())
+		Expect(opts.Frozen()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Options.Freeze", func() {
+	var opts *Options
+
+	BeforeEach(func() {
+		opts = NewOptions(WithOverwrite(false)).Freeze()
+	})
+
+	It("is frozen", func() {
+		Expect(opts.Frozen()).To(BeTrue())
+	})
+
+	It("panics when modified", func() {
+		Expect(func() { opts.SetTypeMergeFunc(reflect.TypeOf(""), erroringMergeFunc) }).To(Panic())
+		Expect(func() { opts.SetKindMergeFunc(reflect.String, erroringMergeFunc) }).To(Panic())
+		Expect(func() { opts.SetDefaultMergeFunc(erroringMergeFunc) }).To(Panic())
+		Expect(func() { opts.SetTypePointerPolicy(reflect.TypeOf(0), PointerFollow) }).To(Panic())
+	})
+
+	It("still merges", func() {
+		target := map[string]int{"a": 1}
+		Expect(Merge(&target, map[string]int{"a": 2, "b": 3}, opts)).To(Succeed())
+		Expect(target).To(Equal(map[string]int{"a": 1, "b": 3}))
+	})
+})
+
+var _ = Describe("concurrent use of Options", func() {
+	type Item struct {
+		Name   string
+		Counts map[string]int
+		Tags   []string
+	}
+
+	merge := func(opts *Options, i int) error {
+		target := map[string]Item{"a": {Name: "a", Counts: map[string]int{"a": 1}}}
+		source := map[string]Item{
+			"a": {Counts: map[string]int{"b": i}, Tags: []string{"x"}},
+			"b": {Name: fmt.Sprint(i)},
+		}
+		return Merge(&target, source, opts)
+	}
+
+	It("shares frozen options between goroutines", func() {
+		opts := NewOptions(WithTypePointerPolicy(reflect.TypeOf(Item{}), PointerFollow)).Freeze()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				errs <- merge(opts, i)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("sets merge funcs while merges are running", func() {
+		opts := NewOptions()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(merge(opts, i)).To(Succeed())
+			}(i)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				opts.SetTypeMergeFunc(reflect.TypeOf(0), defaultMergeFunc)
+				opts.SetTypePointerPolicy(reflect.TypeOf(Item{}), PointerReplace)
+			}()
+		}
+		wg.Wait()
+	})
+
+	It("clones options while merges are running", func() {
+		opts := NewOptions()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(merge(opts, i)).To(Succeed())
+			}(i)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				clone := opts.Clone()
+				clone.SetKindMergeFunc(reflect.Slice, erroringMergeFunc)
+			}()
+		}
+		wg.Wait()
+	})
+})
+
+var _ = Describe("Options.RequiresReflection", func() {
+	It("is false by default", func() {
+		Expect(NewOptions().RequiresReflection()).To(BeFalse())
+	})
+
+	It("is true with hooks, a policy or limits", func() {
+		for _, opt := range []Option{
+			func(o *Options) { o.MaxSliceLen = 1 },
+			func(o *Options) { o.MaxDepth = 1 },
+			func(o *Options) {
+				o.AfterMerge = func(_ string, _, _, r reflect.Value, err error) (reflect.Value, error) { return r, err }
+			},
+			WithPolicy(&Policy{Rules: []PolicyRule{{Kind: "slice", Strategy: StrategyReplace}}}),
+		} {
+			Expect(NewOptions(opt).RequiresReflection()).To(BeTrue())
+		}
+	})
+})
+
+var _ = Describe("Options.UsesDefaultMergeFuncs", func() {
+	types := []reflect.Type{reflect.TypeOf(0), reflect.TypeOf([]string{}), reflect.TypeOf(map[string]int{})}
+
+	It("is true by default", func() {
+		Expect(NewOptions().UsesDefaultMergeFuncs(types...)).To(BeTrue())
+	})
+
+	It("is false with a merge func set for a type, kind or as the default", func() {
+		for _, opt := range []Option{
+			WithTypeFunc(reflect.TypeOf(0), MergeSum),
+			WithKindFunc(reflect.Slice, mergeReplace),
+			WithDefaultFunc(mergeKeep),
+			PresetConfigLayering,
+			PresetNoClobber,
+		} {
+			Expect(NewOptions(opt).UsesDefaultMergeFuncs(types...)).To(BeFalse())
+		}
+	})
+
+	It("is true with merge funcs set for other types", func() {
+		opts := NewOptions(WithKindFunc(reflect.String, mergeKeep))
+		Expect(opts.UsesDefaultMergeFuncs(types...)).To(BeTrue())
+	})
+})
@@ -0,0 +1,265 @@
+package conjungo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OrderedMap is a map with string keys which remembers the order its keys were added in,
+// such as a mapping in a YAML document. The zero OrderedMap is empty and ready to use.
+//
+// When merged, the result has the keys of the target in order, followed by the keys only in
+// the source in their order. The values of keys in both are merged.
+//
+// OrderedMap marshals to and from JSON objects and YAML mappings, keeping the order of
+// their keys.
+type OrderedMap[V any] struct {
+	keys   []string
+	values map[string]V
+}
+
+// Set sets the value of a key. A new key is added after the existing ones, while an
+// existing key keeps its position.
+func (m *OrderedMap[V]) Set(key string, value V) {
+	if m.values == nil {
+		m.values = map[string]V{}
+	}
+
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value of a key and whether it is in the map.
+func (m OrderedMap[V]) Get(key string) (V, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Delete removes a key from the map.
+func (m *OrderedMap[V]) Delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of keys in the map.
+func (m OrderedMap[V]) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys of the map in order.
+func (m OrderedMap[V]) Keys() []string {
+	return append([]string(nil), m.keys...)
+}
+
+// All returns an iterator over the keys and values of the map in order, which calls yield
+// for each until it returns false. With Go 1.23 or later it can be ranged over.
+func (m OrderedMap[V]) All() func(yield func(string, V) bool) {
+	return func(yield func(string, V) bool) {
+		for _, k := range m.keys {
+			if !yield(k, m.values[k]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m OrderedMap[V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object into the map, keeping the order of its keys. A null
+// results in an empty map.
+func (m *OrderedMap[V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		*m = OrderedMap[V]{}
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return errors.New("conjungo: OrderedMap requires a JSON object")
+	}
+
+	var out OrderedMap[V]
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		var v V
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		out.Set(tok.(string), v)
+	}
+
+	*m = out
+	return nil
+}
+
+// MarshalYAML encodes the map as a YAML mapping with its keys in order.
+func (m OrderedMap[V]) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+
+	for _, k := range m.keys {
+		key, val := &yaml.Node{}, &yaml.Node{}
+		if err := key.Encode(k); err != nil {
+			return nil, err
+		}
+		if err := val.Encode(m.values[k]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, key, val)
+	}
+
+	return node, nil
+}
+
+// UnmarshalYAML decodes a YAML mapping into the map, keeping the order of its keys.
+func (m *OrderedMap[V]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: conjungo: OrderedMap requires a YAML mapping", node.Line)
+	}
+
+	var out OrderedMap[V]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var k string
+		if err := node.Content[i].Decode(&k); err != nil {
+			return err
+		}
+
+		var v V
+		if err := node.Content[i+1].Decode(&v); err != nil {
+			return err
+		}
+		out.Set(k, v)
+	}
+
+	*m = out
+	return nil
+}
+
+// orderedMap is implemented by all OrderedMap types, so that a single merge func is found
+// for them.
+type orderedMap interface {
+	orderedType() reflect.Type
+	mergeOrdered(source reflect.Value, o *Options) (reflect.Value, error)
+}
+
+var orderedMapIface = reflect.TypeOf((*orderedMap)(nil)).Elem()
+
+func (m OrderedMap[V]) orderedType() reflect.Type {
+	return reflect.TypeOf(m)
+}
+
+// mergeOrdered merges the source map onto a copy of m as described for OrderedMap.
+func (m OrderedMap[V]) mergeOrdered(source reflect.Value, o *Options) (reflect.Value, error) {
+	src := source.Interface().(OrderedMap[V])
+
+	n := m.Len()
+	for _, k := range src.keys {
+		if _, ok := m.values[k]; !ok {
+			n++
+		}
+	}
+	if err := checkSize(reflect.Map, n, o); err != nil {
+		return reflect.Value{}, err
+	}
+
+	merged := OrderedMap[V]{
+		keys:   make([]string, 0, n),
+		values: make(map[string]V, n),
+	}
+	for _, k := range m.keys {
+		merged.Set(k, m.values[k])
+	}
+
+	for _, k := range src.keys {
+		if err := o.contextErr(); err != nil {
+			return reflect.Value{}, err
+		}
+
+		valS := src.values[k]
+		valT, ok := m.values[k]
+		if !ok {
+			merged.Set(k, valS)
+			continue
+		}
+
+		val, err := mergeChild(reflect.ValueOf(&valT).Elem(), reflect.ValueOf(&valS).Elem(), o, k)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key '%s': %w", k, err)
+		}
+
+		var v V
+		if val.IsValid() {
+			reflect.ValueOf(&v).Elem().Set(val)
+		}
+		merged.Set(k, v)
+	}
+
+	return reflect.ValueOf(merged), nil
+}
+
+// mergeOrderedMap merges two OrderedMap values as described for OrderedMap.
+func mergeOrderedMap(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		return mergePointeeFunc(mergeOrderedMap)(t, s, o)
+	}
+
+	m, ok := t.Interface().(orderedMap)
+	if !ok || m.orderedType() != t.Type() {
+		// a type embedding an OrderedMap
+		return mergeDeep(t, s, o)
+	}
+
+	return m.mergeOrdered(s, o)
+}
@@ -0,0 +1,182 @@
+package conjungo
+
+import (
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+func orderedMapOf[V any](kv ...interface{}) OrderedMap[V] {
+	var m OrderedMap[V]
+	for i := 0; i < len(kv); i += 2 {
+		m.Set(kv[i].(string), kv[i+1].(V))
+	}
+	return m
+}
+
+var _ = Describe("OrderedMap", func() {
+	It("keeps the order of its keys", func() {
+		var m OrderedMap[int]
+		Expect(m.Len()).To(Equal(0))
+
+		m.Set("b", 1)
+		m.Set("a", 2)
+		m.Set("c", 3)
+		m.Set("b", 4)
+		Expect(m.Keys()).To(Equal([]string{"b", "a", "c"}))
+
+		v, ok := m.Get("b")
+		Expect(v).To(Equal(4))
+		Expect(ok).To(BeTrue())
+
+		m.Delete("a")
+		m.Delete("missing")
+		Expect(m.Keys()).To(Equal([]string{"b", "c"}))
+		_, ok = m.Get("a")
+		Expect(ok).To(BeFalse())
+
+		var keys []string
+		var values []int
+		m.All()(func(k string, v int) bool {
+			keys = append(keys, k)
+			values = append(values, v)
+			return true
+		})
+		Expect(keys).To(Equal([]string{"b", "c"}))
+		Expect(values).To(Equal([]int{4, 3}))
+	})
+
+	It("merges with the target order followed by new source keys", func() {
+		target := orderedMapOf[interface{}](
+			"z", 1,
+			"nested", map[string]interface{}{"a": 1},
+			"a", "x",
+		)
+		source := orderedMapOf[interface{}](
+			"b", 2,
+			"a", "y",
+			"nested", map[string]interface{}{"b": 2},
+			"c", 3,
+		)
+
+		err := Merge(&target, source, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Keys()).To(Equal([]string{"z", "nested", "a", "b", "c"}))
+		Expect(target).To(Equal(orderedMapOf[interface{}](
+			"z", 1,
+			"nested", map[string]interface{}{"a": 1, "b": 2},
+			"a", "y",
+			"b", 2,
+			"c", 3,
+		)))
+	})
+
+	It("merges nested ordered maps with paths", func() {
+		type config struct {
+			Services OrderedMap[OrderedMap[int]]
+		}
+
+		var paths []string
+		opts := NewOptions()
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			paths = append(paths, path)
+			return t, s, nil
+		}
+
+		target := config{Services: orderedMapOf[OrderedMap[int]]("web", orderedMapOf[int]("port", 80))}
+		source := config{Services: orderedMapOf[OrderedMap[int]]("web", orderedMapOf[int]("replicas", 2, "port", 8080))}
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		web, _ := target.Services.Get("web")
+		Expect(web).To(Equal(orderedMapOf[int]("port", 8080, "replicas", 2)))
+		Expect(paths).To(ContainElement("Services.web.port"))
+	})
+
+	It("leaves the target intact", func() {
+		target := orderedMapOf[int]("a", 1)
+		original := orderedMapOf[int]("a", 1)
+
+		merged, err := mergeOrderedMap(reflect.ValueOf(target), reflect.ValueOf(orderedMapOf[int]("b", 2)), NewOptions().withState())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged.Interface()).To(Equal(orderedMapOf[int]("a", 1, "b", 2)))
+		Expect(target).To(Equal(original))
+	})
+
+	It("honors limits", func() {
+		opts := NewOptions()
+		opts.MaxMapKeys = 1
+
+		target := orderedMapOf[int]("a", 1)
+		err := Merge(&target, orderedMapOf[int]("b", 2), opts)
+		Expect(err).To(BeAssignableToTypeOf(&LimitError{}))
+	})
+
+	It("merges pointers to ordered maps", func() {
+		target := &OrderedMap[int]{}
+		target.Set("a", 1)
+		source := &OrderedMap[int]{}
+		source.Set("b", 2)
+
+		holder := struct{ M *OrderedMap[int] }{M: target}
+		err := Merge(&holder, struct{ M *OrderedMap[int] }{M: source}, NewOptions(WithPointerPolicy(PointerFollow)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(holder.M).To(BeIdenticalTo(target))
+		Expect(target.Keys()).To(Equal([]string{"a", "b"}))
+	})
+
+	It("round trips through JSON", func() {
+		var m OrderedMap[interface{}]
+		err := json.Unmarshal([]byte(`{"z": 1, "a": {"y": true, "b": null}, "m": [1, 2], "a": "again"}`), &m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Keys()).To(Equal([]string{"z", "a", "m"}))
+
+		data, err := json.Marshal(m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"z":1,"a":"again","m":[1,2]}`))
+
+		var nested OrderedMap[OrderedMap[int]]
+		Expect(json.Unmarshal([]byte(`{"b": {"y": 1, "x": 2}, "a": {}}`), &nested)).To(Succeed())
+		data, err = json.Marshal(nested)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"b":{"y":1,"x":2},"a":{}}`))
+
+		Expect(json.Unmarshal([]byte(`null`), &m)).To(Succeed())
+		Expect(m.Len()).To(Equal(0))
+		Expect(json.Unmarshal([]byte(`[1]`), &m)).To(MatchError("conjungo: OrderedMap requires a JSON object"))
+		Expect(json.Unmarshal([]byte(`{"a": "x"}`), &nested)).ToNot(Succeed())
+	})
+
+	It("round trips through YAML", func() {
+		doc := "z: 1\nnested:\n    y: true\n    b: x\na: [1, 2]\n"
+
+		var m OrderedMap[interface{}]
+		Expect(yaml.Unmarshal([]byte(doc), &m)).To(Succeed())
+		Expect(m.Keys()).To(Equal([]string{"z", "nested", "a"}))
+
+		var nested OrderedMap[OrderedMap[interface{}]]
+		Expect(yaml.Unmarshal([]byte("b: {y: 1, x: 2}\na: {}\n"), &nested)).To(Succeed())
+		data, err := yaml.Marshal(nested)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("b:\n    \"y\": 1\n    x: 2\na: {}\n"))
+
+		Expect(yaml.Unmarshal([]byte("- 1\n"), &m)).To(MatchError(ContainSubstring("line 1: conjungo: OrderedMap requires a YAML mapping")))
+	})
+
+	It("merges configs decoded from YAML in order", func() {
+		var base, override OrderedMap[interface{}]
+		Expect(yaml.Unmarshal([]byte("name: app\nport: 80\nlabels: {tier: web}\n"), &base)).To(Succeed())
+		Expect(yaml.Unmarshal([]byte("debug: true\nport: 8080\n"), &override)).To(Succeed())
+
+		err := Merge(&base, override, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		data, err := yaml.Marshal(base)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("name: app\nport: 8080\nlabels:\n    tier: web\ndebug: true\n"))
+	})
+})
@@ -0,0 +1,115 @@
+package conjungo
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typePlans caches what is worked out from the type of a value when merging it, so that it
+// is only done once per type rather than for every value merged. A funcSelector holds one,
+// which is replaced whenever its merge funcs change.
+type typePlans struct {
+	// reflect.Type -> MergeFunc
+	funcs sync.Map
+
+	// fieldsKey -> []fieldPlan, for mergeStruct
+	fields sync.Map
+
+	// namedKey -> []structField, for MergeFields
+	named sync.Map
+}
+
+// fieldPlan describes a struct field for mergeStruct.
+type fieldPlan struct {
+	field reflect.StructField
+
+	// the name of the field according to Options.TagName
+	name string
+
+	// whether the field is exported and so can be set
+	exported bool
+
+	// whether the field is a pointer to an embedded struct
+	embeddedPtr bool
+
+	// the name of the strategy given in the tag, if any
+	strategy string
+}
+
+type fieldsKey struct {
+	typ     reflect.Type
+	tagName string
+}
+
+type namedKey struct {
+	typ     reflect.Type
+	tagName string
+	squash  bool
+}
+
+func newTypePlans() *typePlans {
+	return &typePlans{}
+}
+
+// plans returns the plans of the options, or nil if they can not be cached.
+func (o *Options) plans() *typePlans {
+	if o == nil || o.mergeFuncs == nil {
+		return nil
+	}
+
+	return o.mergeFuncs.getPlans()
+}
+
+// structFieldPlans returns the plan of each field of the struct type for mergeStruct.
+func structFieldPlans(t reflect.Type, o *Options) []fieldPlan {
+	plans := o.plans()
+	key := fieldsKey{typ: t, tagName: o.TagName}
+
+	if plans != nil {
+		if cached, ok := plans.fields.Load(key); ok {
+			return cached.([]fieldPlan)
+		}
+	}
+
+	fields := make([]fieldPlan, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		name, opts, ok := fieldName(f, o)
+		if !ok {
+			// fields skipped by name are still merged, under their Go name
+			name = f.Name
+		}
+		strategy, _ := opts.Value("strategy")
+
+		fields[i] = fieldPlan{
+			field:       f,
+			name:        name,
+			exported:    f.PkgPath == "",
+			embeddedPtr: isEmbeddedStructPtr(f),
+			strategy:    strategy,
+		}
+	}
+
+	if plans != nil {
+		plans.fields.Store(key, fields)
+	}
+
+	return fields
+}
+
+// cachedStructFields is structFields cached in the plans of the options.
+func cachedStructFields(t reflect.Type, opt *Options) []structField {
+	plans := opt.plans()
+	if plans == nil {
+		return structFields(t, opt)
+	}
+
+	key := namedKey{typ: t, tagName: opt.TagName, squash: opt.Squash}
+	if cached, ok := plans.named.Load(key); ok {
+		return cached.([]structField)
+	}
+
+	fields := structFields(t, opt)
+	plans.named.Store(key, fields)
+	return fields
+}
@@ -0,0 +1,177 @@
+package conjungo
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type plans", func() {
+	type Inner struct {
+		Value string `json:"value"`
+	}
+
+	type Outer struct {
+		Name  string `json:"name"`
+		Inner Inner
+		Tags  map[string]string
+	}
+
+	var opts *Options
+
+	BeforeEach(func() {
+		opts = NewOptions()
+	})
+
+	It("caches the merge func of each type", func() {
+		target := Outer{Name: "a"}
+		err := Merge(&target, Outer{Name: "b"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		f, ok := opts.mergeFuncs.plans.funcs.Load(reflect.TypeOf(Outer{}))
+		Expect(ok).To(BeTrue())
+		Expect(funcName(f.(MergeFunc))).To(Equal(funcName(mergeStruct)))
+
+		_, ok = opts.mergeFuncs.plans.fields.Load(fieldsKey{typ: reflect.TypeOf(Outer{})})
+		Expect(ok).To(BeTrue())
+	})
+
+	It("is invalidated when merge funcs change", func() {
+		target := Outer{Name: "a"}
+		err := Merge(&target, Outer{Name: "b"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("b"))
+
+		opts.SetTypeMergeFunc(reflect.TypeOf(""), func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+			return reflect.ValueOf(t.String() + s.String()), nil
+		})
+		err = Merge(&target, Outer{Name: "c"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("bc"))
+
+		opts.SetKindMergeFunc(reflect.Struct, func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+			return t, nil
+		})
+		err = Merge(&target, Outer{Name: "d"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("bc"))
+
+		plans := opts.mergeFuncs.plans
+		opts.SetDefaultMergeFunc(erroringMergeFunc)
+		Expect(opts.mergeFuncs.plans).ToNot(BeIdenticalTo(plans))
+	})
+
+	It("plans struct fields per tag name", func() {
+		opts.TagName = "json"
+		plans := structFieldPlans(reflect.TypeOf(Outer{}), opts)
+		Expect(plans[0].name).To(Equal("name"))
+
+		opts.TagName = ""
+		plans = structFieldPlans(reflect.TypeOf(Outer{}), opts)
+		Expect(plans[0].name).To(Equal("Name"))
+	})
+
+	It("plans named fields per tag name and squash", func() {
+		opts.TagName = "json"
+		fields := cachedStructFields(reflect.TypeOf(Outer{}), opts)
+		Expect(fields[0].name).To(Equal("name"))
+
+		opts.TagName = ""
+		fields = cachedStructFields(reflect.TypeOf(Outer{}), opts)
+		Expect(fields[0].name).To(Equal("Name"))
+	})
+})
+
+type benchServer struct {
+	Host    string
+	Port    int
+	Enabled *bool
+	Tags    []string
+}
+
+type benchConfig struct {
+	Name     string
+	Replicas int
+	Labels   map[string]string
+	Primary  benchServer
+	Backup   benchServer
+	Limits   [4]int
+	Extra    map[string]interface{}
+}
+
+func newBenchConfigs() (benchConfig, benchConfig) {
+	enabled := true
+	base := benchConfig{
+		Name:     "base",
+		Replicas: 3,
+		Labels:   map[string]string{"app": "api", "tier": "backend"},
+		Primary:  benchServer{Host: "primary", Port: 80, Tags: []string{"a"}},
+		Backup:   benchServer{Host: "backup", Port: 81},
+		Limits:   [4]int{1, 2, 3, 4},
+		Extra:    map[string]interface{}{"debug": false, "nested": map[string]interface{}{"a": 1}},
+	}
+	overrides := benchConfig{
+		Name:    "override",
+		Labels:  map[string]string{"tier": "frontend"},
+		Primary: benchServer{Port: 8080, Enabled: &enabled},
+		Limits:  [4]int{0, 5, 0, 6},
+		Extra:   map[string]interface{}{"debug": true, "nested": map[string]interface{}{"b": 2}},
+	}
+	return base, overrides
+}
+
+func benchmarkMerge(b *testing.B, cached bool) {
+	base, overrides := newBenchConfigs()
+	opts := NewOptions()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			opts.mergeFuncs.plans = nil
+		}
+
+		target := base
+		if err := Merge(&target, overrides, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) { benchmarkMerge(b, false) })
+	b.Run("cached", func(b *testing.B) { benchmarkMerge(b, true) })
+}
+
+func benchmarkMergeFields(b *testing.B, cached bool) {
+	var target benchConfig
+	source := map[string]interface{}{
+		"name":     "override",
+		"replicas": 5,
+		"labels":   map[string]interface{}{"tier": "frontend"},
+		"primary":  map[string]interface{}{"host": "primary", "port": 8080},
+	}
+	opts := NewOptions()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			opts.mergeFuncs.plans = nil
+		}
+
+		target.Labels = nil
+		if err := MergeFields(&target, source, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMergeFields(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) { benchmarkMergeFields(b, false) })
+	b.Run("cached", func(b *testing.B) { benchmarkMergeFields(b, true) })
+}
@@ -0,0 +1,126 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PointerPolicy determines how two non-nil pointers are merged.
+// A nil target pointer is always replaced by the source pointer, and a nil source
+// pointer always leaves the target as is.
+type PointerPolicy int
+
+const (
+	// PointerReplace treats pointers like any other value: the target pointer is replaced
+	// by the source pointer if Overwrite is set. This is the default.
+	PointerReplace PointerPolicy = iota
+
+	// PointerFollow merges the values the pointers point to and writes the result through
+	// the target pointer, preserving the address held by the target. Note that this
+	// modifies the value the target points to as the merge progresses.
+	PointerFollow
+
+	// PointerAllocate merges the values the pointers point to into a newly allocated
+	// value, leaving the values that the target and source point to untouched.
+	PointerAllocate
+)
+
+func (p PointerPolicy) String() string {
+	switch p {
+	case PointerReplace:
+		return "replace"
+	case PointerFollow:
+		return "follow"
+	case PointerAllocate:
+		return "allocate"
+	}
+	return fmt.Sprintf("PointerPolicy(%d)", int(p))
+}
+
+// SetTypePointerPolicy is used to define how pointers of a particular type are merged,
+// overriding Options.PointerPolicy. Accepts either the reflect.Type of the pointer or of
+// the type it points to.
+func (o *Options) SetTypePointerPolicy(t reflect.Type, p PointerPolicy) {
+	o.mergeFuncs.setTypePointerPolicy(t, p)
+}
+
+// pointerPolicy returns the policy for pointers of the given type.
+func (o *Options) pointerPolicy(t reflect.Type) PointerPolicy {
+	if p, ok := o.mergeFuncs.getTypePointerPolicy(t); ok {
+		return p
+	}
+
+	return o.PointerPolicy
+}
+
+// Merges two pointers of the same type according to the pointer policy for the type.
+// See PointerPolicy for the available behaviors.
+func mergePtr(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() != reflect.Ptr || t.Type() != s.Type() {
+		return reflect.Value{}, fmt.Errorf("pointers must have same type: T: %v S: %v", t.Type(), s.Type())
+	}
+
+	policy := o.pointerPolicy(t.Type())
+	if policy == PointerReplace || t.IsNil() || s.IsNil() {
+		return defaultMergeFunc(t, s, o)
+	}
+
+	return mergeElems(t, s, o, merge, policy == PointerFollow)
+}
+
+// mergeElems merges the values pointed to by t and s with mf, and returns a pointer to the
+// result: t itself if follow is set, or a newly allocated pointer otherwise, which takes the
+// place of t where the merged values refer back to it.
+func mergeElems(t, s reflect.Value, o *Options, mf MergeFunc, follow bool) (reflect.Value, error) {
+	ptr := t
+	if !follow {
+		ptr = reflect.New(t.Type().Elem())
+		o.resolveCycles(t, s, ptr)
+	}
+
+	merged, err := mf(t.Elem(), s.Elem(), o)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if !merged.IsValid() {
+		return t, nil
+	}
+
+	elemType := t.Type().Elem()
+	if elemType.Kind() != reflect.Interface && elemType != merged.Type() {
+		return reflect.Value{}, fmt.Errorf("types dont match %v <> %v", elemType, merged.Type())
+	}
+
+	ptr.Elem().Set(merged)
+	return ptr, nil
+}
+
+// mergePointeeFunc returns a merge func for pointers which merges the values they point to
+// with the given merge func under PointerFollow and PointerAllocate. Under PointerReplace
+// the pointers are merged like any other value.
+func mergePointeeFunc(mf MergeFunc) MergeFunc {
+	elems := mergeElemsFunc(mf)
+
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		if o.pointerPolicy(t.Type()) == PointerReplace {
+			return defaultMergeFunc(t, s, o)
+		}
+
+		return elems(t, s, o)
+	}
+}
+
+// mergeElemsFunc returns a merge func for pointers which merges the values they point to
+// with the given merge func. The result is set through the target pointer if the pointer
+// policy is PointerFollow, and on a newly allocated value otherwise. Nil pointers are
+// merged with the default func.
+func mergeElemsFunc(mf MergeFunc) MergeFunc {
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		if t.IsNil() || s.IsNil() {
+			return defaultMergeFunc(t, s, o)
+		}
+
+		return mergeElems(t, s, o, mf, o.pointerPolicy(t.Type()) == PointerFollow)
+	}
+}
@@ -0,0 +1,134 @@
+package conjungo
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("mergePtr", func() {
+	type Settings struct {
+		Name   string
+		Labels map[string]string
+	}
+
+	type Config struct {
+		Settings *Settings
+		Count    *int
+	}
+
+	var (
+		opts           *Options
+		target, source Config
+		origSettings   *Settings
+	)
+
+	intPtr := func(i int) *int { return &i }
+
+	BeforeEach(func() {
+		opts = NewOptions()
+		origSettings = &Settings{Name: "target", Labels: map[string]string{"a": "1"}}
+		target = Config{Settings: origSettings, Count: intPtr(1)}
+		source = Config{Settings: &Settings{Labels: map[string]string{"b": "2"}}, Count: intPtr(2)}
+	})
+
+	Context("replace", func() {
+		It("replaces the target pointer", func() {
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Settings).To(BeIdenticalTo(source.Settings))
+			Expect(target.Count).To(BeIdenticalTo(source.Count))
+		})
+
+		It("keeps the target pointer without overwrite", func() {
+			opts.Overwrite = false
+
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Settings).To(BeIdenticalTo(origSettings))
+			Expect(origSettings.Labels).To(Equal(map[string]string{"a": "1"}))
+		})
+	})
+
+	Context("follow", func() {
+		BeforeEach(func() {
+			opts.PointerPolicy = PointerFollow
+		})
+
+		It("merges into the target pointee", func() {
+			origCount := target.Count
+
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Settings).To(BeIdenticalTo(origSettings))
+			Expect(*origSettings).To(Equal(Settings{Name: "", Labels: map[string]string{"a": "1", "b": "2"}}))
+			Expect(target.Count).To(BeIdenticalTo(origCount))
+			Expect(*origCount).To(Equal(2))
+		})
+
+		It("takes the source pointer if the target is nil", func() {
+			target.Settings = nil
+
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Settings).To(BeIdenticalTo(source.Settings))
+		})
+
+		It("returns errors from the pointee", func() {
+			opts.SetTypeMergeFunc(reflect.TypeOf(0), erroringMergeFunc)
+
+			err := Merge(&target, source, opts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to merge field `Config.Count`: returns error"))
+		})
+	})
+
+	Context("allocate", func() {
+		BeforeEach(func() {
+			opts.PointerPolicy = PointerAllocate
+		})
+
+		It("merges into a new value", func() {
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Settings).ToNot(BeIdenticalTo(origSettings))
+			Expect(target.Settings).ToNot(BeIdenticalTo(source.Settings))
+			Expect(target.Settings.Labels).To(Equal(map[string]string{"a": "1", "b": "2"}))
+			Expect(origSettings.Name).To(Equal("target"))
+			Expect(*target.Count).To(Equal(2))
+		})
+	})
+
+	Context("per type", func() {
+		It("overrides the default policy", func() {
+			opts.PointerPolicy = PointerAllocate
+			opts.SetTypePointerPolicy(reflect.TypeOf(0), PointerReplace)
+			opts.SetTypePointerPolicy(reflect.TypeOf(&Settings{}), PointerFollow)
+
+			err := Merge(&target, source, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Settings).To(BeIdenticalTo(origSettings))
+			Expect(target.Count).To(BeIdenticalTo(source.Count))
+		})
+	})
+
+	It("errors on different types", func() {
+		_, err := mergePtr(reflect.ValueOf(intPtr(1)), reflect.ValueOf(&Settings{}), opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("pointers must have same type"))
+	})
+})
+
+var _ = Describe("PointerPolicy", func() {
+	DescribeTable("String",
+		func(p PointerPolicy, expected string) {
+			Expect(p.String()).To(Equal(expected))
+		},
+		Entry("replace", PointerReplace, "replace"),
+		Entry("follow", PointerFollow, "follow"),
+		Entry("allocate", PointerAllocate, "allocate"),
+		Entry("unknown", PointerPolicy(9), "PointerPolicy(9)"),
+	)
+})
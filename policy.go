@@ -0,0 +1,625 @@
+package conjungo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Conflict modes of a policy rule, which determine what happens when both the target and the
+// source have a value.
+const (
+	// ConflictModeOverwrite uses the source value, as if Options.Overwrite was set.
+	ConflictModeOverwrite = "overwrite"
+
+	// ConflictModeKeep keeps the target value, as if Options.Overwrite was not set.
+	ConflictModeKeep = "keep"
+
+	// ConflictModeError fails the merge with a ConflictError if the values differ.
+	ConflictModeError = "error"
+)
+
+// ConflictError is returned when values differ under a policy rule with the conflict mode
+// ConflictModeError.
+type ConflictError struct {
+	// Path is the path of the conflicting values. See Options.Path.
+	Path string
+
+	Target, Source interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting values at %s: %v and %v", e.Path, e.Target, e.Source)
+}
+
+// A Policy describes how values are merged in a document, so that merge rules can be
+// authored outside of Go. It is usually decoded from YAML or JSON with LoadPolicy.
+type Policy struct {
+	// Sets Options.Overwrite, if given.
+	Overwrite *bool `yaml:"overwrite"`
+
+	// Sets Options.TagName, which also names the struct fields in rule paths.
+	TagName string `yaml:"tagName"`
+
+	// The rules, of which the first one matching a value is used to merge it.
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// A PolicyRule selects values by path, type or kind, and says how to merge them.
+//
+// Paths are written like Options.Path: struct fields and map keys are separated by dots,
+// and indexes are given in brackets, such as `servers[0].ports`. A `*` matches any field
+// or key, and `[*]` any index. Where a path leads to a pointer, the rule applies to the
+// values it points to, which are set according to the pointer policy.
+type PolicyRule struct {
+	// Only one of these selects the values the rule applies to. Types are given by their
+	// reflect.Type string, such as `time.Duration` or `[]string`, and kinds by their
+	// reflect.Kind string, such as `slice`.
+	Path string `yaml:"path"`
+	Type string `yaml:"type"`
+	Kind string `yaml:"kind"`
+
+	// The name of a registered strategy used to merge the values. See LookupStrategy.
+	// If empty, the merge func for their type is used.
+	Strategy string `yaml:"strategy"`
+
+	// One of ConflictModeOverwrite, ConflictModeKeep or ConflictModeError, which applies to
+	// the values and everything in them. ConflictModeError applies where values are merged
+	// by the default merge func.
+	Conflict string `yaml:"conflict"`
+
+	// For slices of structs or maps, merges the elements which have the same value of this
+	// field or key, and appends the others. Can not be combined with a strategy.
+	Key string `yaml:"key"`
+}
+
+// policy is a Policy compiled for use in a merge.
+type policy struct {
+	rules []policyRule
+}
+
+type policyRule struct {
+	path []string
+	typ  string
+	kind reflect.Kind
+
+	mf MergeFunc
+
+	// mf for pointers, for path rules
+	ptrMF MergeFunc
+}
+
+// LoadPolicy decodes a Policy from YAML or JSON and returns Options that merge according to
+// it. Any error in the policy, such as an unknown strategy, is returned.
+func LoadPolicy(r io.Reader) (*Options, error) {
+	p, err := DecodePolicy(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.newOptions()
+}
+
+// LoadPolicyFor is like LoadPolicy, but also validates the policy against the type of the
+// given target, so that rules with paths which do not exist in it are reported as errors.
+func LoadPolicyFor(r io.Reader, target interface{}) (*Options, error) {
+	p, err := DecodePolicy(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.Validate(reflect.TypeOf(target)); err != nil {
+		return nil, err
+	}
+
+	return p.newOptions()
+}
+
+// DecodePolicy decodes a Policy from YAML or JSON. Unknown fields are errors.
+func DecodePolicy(r io.Reader) (*Policy, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	p := &Policy{}
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+
+	return p, nil
+}
+
+// WithPolicy applies a Policy to the options. It panics if the policy is invalid; use
+// Policy.Validate to check it first.
+func WithPolicy(p *Policy) Option {
+	compiled, err := p.compile()
+	if err != nil {
+		panic("conjungo: " + err.Error())
+	}
+
+	return func(o *Options) {
+		p.apply(o, compiled)
+	}
+}
+
+// Validate checks the rules of the policy, and that their paths exist in the given type,
+// which may be nil to only check the rules themselves.
+func (p *Policy) Validate(t reflect.Type) error {
+	compiled, err := p.compile()
+	if err != nil {
+		return err
+	}
+
+	if t == nil {
+		return nil
+	}
+
+	opt := &Options{TagName: p.TagName}
+	for i, rule := range compiled.rules {
+		if rule.path == nil {
+			continue
+		}
+
+		elem, err := resolvePath(t, rule.path, opt)
+		if err != nil {
+			return fmt.Errorf("policy rule %d: path %q does not exist in %v: %w", i, p.Rules[i].Path, t, err)
+		}
+
+		if key := p.Rules[i].Key; key != "" && elem != nil {
+			if err := checkKeyField(elem, key, opt); err != nil {
+				return fmt.Errorf("policy rule %d: path %q: %w", i, p.Rules[i].Path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) newOptions() (*Options, error) {
+	compiled, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	o := NewOptions()
+	p.apply(o, compiled)
+	return o, nil
+}
+
+func (p *Policy) apply(o *Options, compiled *policy) {
+	if p.Overwrite != nil {
+		o.Overwrite = *p.Overwrite
+	}
+	if p.TagName != "" {
+		o.TagName = p.TagName
+	}
+
+	o.policy = compiled
+}
+
+func (p *Policy) compile() (*policy, error) {
+	compiled := &policy{rules: make([]policyRule, len(p.Rules))}
+
+	for i, r := range p.Rules {
+		rule, err := r.compile()
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %d: %w", i, err)
+		}
+		compiled.rules[i] = rule
+	}
+
+	return compiled, nil
+}
+
+func (r PolicyRule) compile() (policyRule, error) {
+	var rule policyRule
+
+	selectors := 0
+	if r.Path != "" {
+		selectors++
+		path, err := splitPath(r.Path)
+		if err != nil {
+			return rule, err
+		}
+		rule.path = path
+	}
+	if r.Type != "" {
+		selectors++
+		rule.typ = r.Type
+	}
+	if r.Kind != "" {
+		selectors++
+		k, ok := kindsByName[r.Kind]
+		if !ok {
+			return rule, fmt.Errorf("unknown kind %q", r.Kind)
+		}
+		rule.kind = k
+	}
+	if selectors != 1 {
+		return rule, errors.New("exactly one of path, type or kind is required")
+	}
+
+	var mf MergeFunc
+	switch {
+	case r.Strategy != "" && r.Key != "":
+		return rule, errors.New("a strategy can not be combined with a key")
+	case r.Strategy != "":
+		var err error
+		if mf, err = strategy(r.Strategy); err != nil {
+			return rule, err
+		}
+	case r.Key != "":
+		mf = mergeSliceByKey(r.Key)
+	}
+
+	switch r.Conflict {
+	case "", ConflictModeOverwrite, ConflictModeKeep, ConflictModeError:
+	default:
+		return rule, fmt.Errorf("unknown conflict mode %q", r.Conflict)
+	}
+
+	if mf == nil && r.Conflict == "" {
+		return rule, errors.New("one of strategy, key or conflict is required")
+	}
+
+	rule.mf = conflictFunc(r.Conflict, mf)
+	rule.ptrMF = mergeElemsFunc(rule.mf)
+	return rule, nil
+}
+
+// conflictFunc returns a merge func that merges with mf, or the merge func for the type if
+// it is nil, under the given conflict mode.
+func conflictFunc(mode string, mf MergeFunc) MergeFunc {
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		if mode != "" {
+			cp := *o
+			cp.Overwrite = mode != ConflictModeKeep
+			cp.errorOnConflict = mode == ConflictModeError
+			o = &cp
+		}
+
+		f := adaptStrategy(mf, t.Type())
+		if f == nil {
+			f = o.mergeFuncs.getFunc(t)
+		}
+
+		return f(t, s, o)
+	}
+}
+
+// funcFor returns the merge func of the first rule matching a value of the given type at
+// the given path, or nil if there is none.
+func (p *policy) funcFor(path string, t reflect.Type) MergeFunc {
+	var segments []string
+
+	for _, rule := range p.rules {
+		switch {
+		case rule.path != nil:
+			if segments == nil {
+				// paths of values are well formed
+				segments, _ = splitPath(path)
+			}
+			if !matchPath(rule.path, segments) {
+				continue
+			}
+			if t.Kind() == reflect.Ptr {
+				return rule.ptrMF
+			}
+			return rule.mf
+
+		case rule.typ != "":
+			if t.String() == rule.typ {
+				return rule.mf
+			}
+
+		case rule.kind == t.Kind():
+			return rule.mf
+		}
+	}
+
+	return nil
+}
+
+// typeFuncFor returns the merge func of the first type or kind rule matching the type, or
+// nil if there is none.
+func (p *policy) typeFuncFor(t reflect.Type) MergeFunc {
+	for _, rule := range p.rules {
+		switch {
+		case rule.path != nil:
+			continue
+
+		case rule.typ != "":
+			if t.String() == rule.typ {
+				return rule.mf
+			}
+
+		case rule.kind == t.Kind():
+			return rule.mf
+		}
+	}
+
+	return nil
+}
+
+// splitPath splits a path into its fields, keys and bracketed indexes.
+func splitPath(path string) ([]string, error) {
+	segments := []string{}
+
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+
+		if name == "" && (part == "" || len(segments) > 0) {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		if name != "" {
+			segments = append(segments, name)
+		}
+
+		for rest := part[len(name):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+
+			index := rest[:end+1]
+			if _, err := strconv.Atoi(index[1:end]); err != nil && index != "[*]" {
+				return nil, fmt.Errorf("invalid index %s in path %q", index, path)
+			}
+
+			segments = append(segments, index)
+			rest = rest[end+1:]
+		}
+	}
+
+	return segments, nil
+}
+
+func matchPath(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+
+	for i, p := range pattern {
+		s := segments[i]
+		switch {
+		case p == s:
+		case p == "*" && !isIndex(s):
+		case p == "[*]" && isIndex(s):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isIndex(segment string) bool {
+	return strings.HasPrefix(segment, "[")
+}
+
+// resolvePath returns the type of the values at the path in the given type, or nil if it
+// can not be known because the path goes through an interface.
+func resolvePath(t reflect.Type, path []string, opt *Options) (reflect.Type, error) {
+	for i, segment := range path {
+		t = indirectType(t)
+		if t == yamlNodeType {
+			// a YAML node may hold any value
+			return nil, nil
+		}
+
+		switch t.Kind() {
+		case reflect.Interface:
+			return nil, nil
+
+		case reflect.Struct:
+			if isIndex(segment) {
+				return nil, fmt.Errorf("%v has no index %s", t, segment)
+			}
+
+			if segment == "*" {
+				return resolveAnyField(t, path[i+1:], opt)
+			}
+
+			field, ok := fieldPlanByName(t, segment, opt)
+			if !ok {
+				return nil, fmt.Errorf("%v has no field %q", t, segment)
+			}
+			t = field.Type
+
+		case reflect.Map:
+			if isIndex(segment) {
+				return nil, fmt.Errorf("%v has no index %s", t, segment)
+			}
+			t = t.Elem()
+
+		case reflect.Slice, reflect.Array:
+			if !isIndex(segment) {
+				return nil, fmt.Errorf("%v has no field %q", t, segment)
+			}
+			t = t.Elem()
+
+		default:
+			return nil, fmt.Errorf("%v has no field or key %q", t, segment)
+		}
+	}
+
+	return indirectType(t), nil
+}
+
+// resolveAnyField resolves the rest of a path in any field of the struct type.
+func resolveAnyField(t reflect.Type, path []string, opt *Options) (reflect.Type, error) {
+	var firstErr error
+
+	for _, plan := range structFieldPlans(t, opt) {
+		if !plan.exported {
+			continue
+		}
+
+		resolved, err := resolvePath(plan.field.Type, path, opt)
+		if err == nil {
+			return resolved, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = fmt.Errorf("%v has no exported fields", t)
+	}
+
+	return nil, firstErr
+}
+
+func fieldPlanByName(t reflect.Type, name string, opt *Options) (reflect.StructField, bool) {
+	for _, plan := range structFieldPlans(t, opt) {
+		if plan.name == name {
+			return plan.field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// checkKeyField checks that the elements of the slice type have the key field.
+func checkKeyField(t reflect.Type, key string, opt *Options) error {
+	if t.Kind() != reflect.Slice {
+		return fmt.Errorf("a key requires a slice, got %v", t)
+	}
+
+	elem := indirectType(t.Elem())
+	switch elem.Kind() {
+	case reflect.Struct:
+		if _, ok := structFieldsByKey(elem, opt)[key]; !ok {
+			return fmt.Errorf("%v has no key field %q", elem, key)
+		}
+	case reflect.Map:
+		if elem.Key().Kind() != reflect.String {
+			return fmt.Errorf("a key requires maps with string keys, got %v", elem)
+		}
+	case reflect.Interface:
+	default:
+		return fmt.Errorf("a key requires slices of structs or maps, got %v", t)
+	}
+
+	return nil
+}
+
+// mergeSliceByKey returns a merge func for slices which merges the elements of the source
+// onto the elements of the target with the same value of the key field, and appends the
+// elements that have no match. Elements without the key are appended.
+func mergeSliceByKey(key string) MergeFunc {
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		if t.Kind() != reflect.Slice || t.Type() != s.Type() {
+			return reflect.Value{}, fmt.Errorf("merging by key %q requires slices of the same type, got %v", key, t.Type())
+		}
+
+		merged := reflect.MakeSlice(t.Type(), 0, t.Len()+s.Len())
+		merged = reflect.AppendSlice(merged, t)
+
+		index := map[interface{}]int{}
+		for i := 0; i < t.Len(); i++ {
+			k, ok, err := keyValue(t.Index(i), key, o)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			if ok {
+				if _, dup := index[k]; !dup {
+					index[k] = i
+				}
+			}
+		}
+
+		for j := 0; j < s.Len(); j++ {
+			if err := o.contextErr(); err != nil {
+				return reflect.Value{}, err
+			}
+
+			elemS := s.Index(j)
+			k, ok, err := keyValue(elemS, key, o)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", j, err)
+			}
+
+			i, found := index[k]
+			if !ok || !found {
+				if ok {
+					index[k] = merged.Len()
+				}
+				merged = reflect.Append(merged, elemS)
+				continue
+			}
+
+			val, err := mergeChild(merged.Index(i), elemS, o, fmt.Sprintf("[%d]", i))
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			if val.IsValid() {
+				merged.Index(i).Set(val)
+			}
+		}
+
+		if err := checkSize(reflect.Slice, merged.Len(), o); err != nil {
+			return reflect.Value{}, err
+		}
+
+		return merged, nil
+	}
+}
+
+// keyValue returns the value of the key field of a struct or map element. It reports false
+// if the element has no key.
+func keyValue(elem reflect.Value, key string, o *Options) (interface{}, bool, error) {
+	for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+		if elem.IsNil() {
+			return nil, false, nil
+		}
+		elem = elem.Elem()
+	}
+
+	var k reflect.Value
+	switch elem.Kind() {
+	case reflect.Struct:
+		index, ok := structFieldsByKey(elem.Type(), o)[key]
+		if !ok {
+			return nil, false, fmt.Errorf("%v has no key field %q", elem.Type(), key)
+		}
+		k = elem.FieldByIndex(index)
+
+	case reflect.Map:
+		if elem.Type().Key().Kind() != reflect.String {
+			return nil, false, fmt.Errorf("merging by key requires maps with string keys, got %v", elem.Type())
+		}
+		k = elem.MapIndex(reflect.ValueOf(key).Convert(elem.Type().Key()))
+
+	default:
+		return nil, false, fmt.Errorf("merging by key requires structs or maps, got %v", elem.Type())
+	}
+
+	for k.IsValid() && (k.Kind() == reflect.Ptr || k.Kind() == reflect.Interface) && !k.IsNil() {
+		k = k.Elem()
+	}
+	if !k.IsValid() || isEmpty(k) {
+		return nil, false, nil
+	}
+	if !k.Type().Comparable() {
+		return nil, false, fmt.Errorf("key %q of type %v is not comparable", key, k.Type())
+	}
+
+	return k.Interface(), true, nil
+}
+
+var kindsByName = func() map[string]reflect.Kind {
+	kinds := map[string]reflect.Kind{}
+	for k := reflect.Bool; k <= reflect.UnsafePointer; k++ {
+		kinds[k.String()] = k
+	}
+	return kinds
+}()
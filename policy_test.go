@@ -0,0 +1,292 @@
+package conjungo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type policyServer struct {
+	Name    string            `yaml:"name"`
+	Port    int               `yaml:"port"`
+	Tags    []string          `yaml:"tags"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+type policyConfig struct {
+	Version string                    `yaml:"version"`
+	Servers []policyServer            `yaml:"servers"`
+	Limits  map[string]int            `yaml:"limits"`
+	Primary *policyServer             `yaml:"primary"`
+	Extra   map[string]interface{}    `yaml:"extra"`
+	Groups  map[string][]policyServer `yaml:"groups"`
+}
+
+const configPolicy = `
+tagName: yaml
+rules:
+  - path: servers
+    key: name
+  - path: servers[*].tags
+    strategy: union
+  - path: limits.*
+    strategy: max
+  - path: version
+    conflict: error
+  - type: time.Duration
+    strategy: max
+`
+
+var _ = Describe("LoadPolicy", func() {
+	It("merges according to the policy", func() {
+		opts, err := LoadPolicyFor(strings.NewReader(configPolicy), policyConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		target := policyConfig{
+			Version: "1",
+			Servers: []policyServer{
+				{Name: "a", Port: 80, Tags: []string{"x"}, Timeout: time.Second},
+				{Name: "b", Port: 81},
+			},
+			Limits: map[string]int{"cpu": 2, "mem": 8},
+		}
+		source := policyConfig{
+			Version: "1",
+			Servers: []policyServer{
+				{Name: "a", Port: 8080, Tags: []string{"x", "y"}, Timeout: time.Millisecond},
+				{Name: "c", Port: 82},
+			},
+			Limits: map[string]int{"cpu": 4, "mem": 4},
+		}
+
+		err = Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(policyConfig{
+			Version: "1",
+			Servers: []policyServer{
+				{Name: "a", Port: 8080, Tags: []string{"x", "y"}, Timeout: time.Second},
+				{Name: "b", Port: 81},
+				{Name: "c", Port: 82},
+			},
+			Limits: map[string]int{"cpu": 4, "mem": 8},
+		}))
+	})
+
+	It("fails on conflicts", func() {
+		opts, err := LoadPolicy(strings.NewReader(configPolicy))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := policyConfig{Version: "1"}
+		err = Merge(&target, policyConfig{Version: "2"}, opts)
+		Expect(err).To(MatchError("failed to merge field `policyConfig.version`: conflicting values at version: 1 and 2"))
+		Expect(target.Version).To(Equal("1"))
+
+		var conflict *ConflictError
+		Expect(errors.As(err, &conflict)).To(BeTrue())
+		Expect(conflict.Path).To(Equal("version"))
+	})
+
+	It("keeps values where there is no conflict", func() {
+		opts, err := LoadPolicy(strings.NewReader(configPolicy))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := policyConfig{Version: "1"}
+		Expect(Merge(&target, policyConfig{}, opts)).To(Succeed())
+		Expect(target.Version).To(Equal("1"))
+
+		target = policyConfig{}
+		Expect(Merge(&target, policyConfig{Version: "2"}, opts)).To(Succeed())
+		Expect(target.Version).To(Equal("2"))
+	})
+
+	It("keeps target values in a subtree", func() {
+		opts, err := LoadPolicy(strings.NewReader(`
+rules:
+  - path: Primary
+    conflict: keep
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := policyConfig{Version: "1", Primary: &policyServer{Name: "a", Port: 80}}
+		err = Merge(&target, policyConfig{Version: "2", Primary: &policyServer{Name: "b", Port: 81, Tags: []string{"x"}}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Version).To(Equal("2"))
+		Expect(*target.Primary).To(Equal(policyServer{Name: "a", Port: 80, Tags: []string{"x"}}))
+	})
+
+	It("applies rules by kind and options", func() {
+		opts, err := LoadPolicy(strings.NewReader(`{"overwrite": false, "rules": [{"kind": "slice", "strategy": "replace"}]}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.Overwrite).To(BeFalse())
+
+		target := policyServer{Name: "a", Tags: []string{"x"}}
+		err = Merge(&target, policyServer{Name: "b", Tags: []string{"y"}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(policyServer{Name: "a", Tags: []string{"y"}}))
+	})
+
+	It("uses the first matching rule", func() {
+		opts, err := LoadPolicy(strings.NewReader(`
+rules:
+  - path: Servers
+    strategy: keep
+  - kind: slice
+    strategy: replace
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := policyConfig{Servers: []policyServer{{Name: "a"}}}
+		err = Merge(&target, policyConfig{Servers: []policyServer{{Name: "b"}}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Servers).To(Equal([]policyServer{{Name: "a"}}))
+	})
+
+	It("merges slices of maps by key", func() {
+		opts, err := LoadPolicy(strings.NewReader(`
+rules:
+  - path: items
+    key: id
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"id": "a", "value": 1},
+			map[string]interface{}{"value": 2},
+		}}
+		source := map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"id": "a", "other": 3},
+			map[string]interface{}{"id": "b"},
+		}}
+
+		err = Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"id": "a", "value": 1, "other": 3},
+			map[string]interface{}{"value": 2},
+			map[string]interface{}{"id": "b"},
+		}}))
+	})
+
+	It("reports merge paths of keyed elements", func() {
+		opts, err := LoadPolicy(strings.NewReader(`
+rules:
+  - path: Servers
+    key: Name
+  - path: Servers[*].Port
+    conflict: error
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := policyConfig{Servers: []policyServer{{Name: "a", Port: 1}, {Name: "b", Port: 1}}}
+		err = Merge(&target, policyConfig{Servers: []policyServer{{Name: "b", Port: 2}}}, opts)
+		Expect(err).To(MatchError(ContainSubstring("conflicting values at Servers[1].Port: 1 and 2")))
+	})
+
+	It("can be applied as an Option", func() {
+		overwrite := false
+		opts := NewOptions(WithPolicy(&Policy{
+			Overwrite: &overwrite,
+			Rules:     []PolicyRule{{Type: "int", Strategy: StrategySum}},
+		}))
+
+		target := policyServer{Name: "a", Port: 1}
+		err := Merge(&target, policyServer{Name: "b", Port: 2}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(policyServer{Name: "a", Port: 3}))
+
+		Expect(func() { WithPolicy(&Policy{Rules: []PolicyRule{{Kind: "slice"}}}) }).To(Panic())
+	})
+
+	It("accepts an empty policy", func() {
+		opts, err := LoadPolicy(strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.Overwrite).To(BeTrue())
+	})
+
+	DescribeTable("invalid policies",
+		func(doc, msg string) {
+			_, err := LoadPolicy(strings.NewReader(doc))
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("unknown fields", "rules: [{path: a, strat: sum}]", "field strat not found"),
+		Entry("no selector", "rules: [{strategy: sum}]", "policy rule 0: exactly one of path, type or kind is required"),
+		Entry("several selectors", "rules: [{path: a, kind: map, strategy: sum}]", "exactly one of path, type or kind is required"),
+		Entry("no action", "rules: [{path: a}]", "policy rule 0: one of strategy, key or conflict is required"),
+		Entry("unknown strategy", "rules: [{path: a, strategy: sum}, {path: c, strategy: nope}]", "policy rule 1: unknown merge strategy \"nope\""),
+		Entry("unknown kind", "rules: [{kind: thing, strategy: sum}]", "unknown kind \"thing\""),
+		Entry("unknown conflict", "rules: [{path: a, conflict: maybe}]", "unknown conflict mode \"maybe\""),
+		Entry("strategy and key", "rules: [{path: a, strategy: sum, key: b}]", "a strategy can not be combined with a key"),
+		Entry("invalid path", "rules: [{path: a..b, strategy: sum}]", "invalid path \"a..b\""),
+		Entry("invalid index", "rules: [{path: 'a[x]', strategy: sum}]", "invalid index [x] in path \"a[x]\""),
+	)
+})
+
+var _ = Describe("Policy.Validate", func() {
+	configType := reflect.TypeOf(policyConfig{})
+
+	validate := func(path, key string) error {
+		return (&Policy{
+			TagName: "yaml",
+			Rules:   []PolicyRule{{Path: path, Key: key, Conflict: ConflictModeKeep}},
+		}).Validate(configType)
+	}
+
+	DescribeTable("existing paths",
+		func(path, key string) {
+			Expect(validate(path, key)).To(Succeed())
+		},
+		Entry("field", "version", ""),
+		Entry("nested field", "servers[*].port", ""),
+		Entry("indexed field", "servers[0].labels.app", ""),
+		Entry("through pointers", "primary.tags[2]", ""),
+		Entry("map key", "limits.cpu", ""),
+		Entry("any field", "primary.*", ""),
+		Entry("any field with a nested path", "primary.*.app", ""),
+		Entry("through interfaces", "extra.a.b[0].c", ""),
+		Entry("slice key", "servers", "name"),
+		Entry("slice key by go name", "servers", "Port"),
+		Entry("slices in maps", "groups.*", "name"),
+	)
+
+	DescribeTable("missing paths",
+		func(path, key, msg string) {
+			Expect(validate(path, key)).To(MatchError(msg))
+		},
+		Entry("field", "versions", "",
+			`policy rule 0: path "versions" does not exist in conjungo.policyConfig: conjungo.policyConfig has no field "versions"`),
+		Entry("go name", "Version", "",
+			`policy rule 0: path "Version" does not exist in conjungo.policyConfig: conjungo.policyConfig has no field "Version"`),
+		Entry("nested field", "servers[*].host", "",
+			`policy rule 0: path "servers[*].host" does not exist in conjungo.policyConfig: conjungo.policyServer has no field "host"`),
+		Entry("field of a slice", "servers.port", "",
+			`policy rule 0: path "servers.port" does not exist in conjungo.policyConfig: []conjungo.policyServer has no field "port"`),
+		Entry("index of a struct", "primary[0]", "",
+			`policy rule 0: path "primary[0]" does not exist in conjungo.policyConfig: conjungo.policyServer has no index [0]`),
+		Entry("past a scalar", "version.major", "",
+			`policy rule 0: path "version.major" does not exist in conjungo.policyConfig: string has no field or key "major"`),
+		Entry("any field", "primary.*.a.b", "",
+			`policy rule 0: path "primary.*.a.b" does not exist in conjungo.policyConfig: string has no field or key "a"`),
+		Entry("key of a map", "limits", "name",
+			`policy rule 0: path "limits": a key requires a slice, got map[string]int`),
+		Entry("key of scalars", "servers[*].tags", "name",
+			`policy rule 0: path "servers[*].tags": a key requires slices of structs or maps, got []string`),
+		Entry("missing key field", "servers", "host",
+			`policy rule 0: path "servers": conjungo.policyServer has no key field "host"`),
+	)
+
+	It("checks rules without a type", func() {
+		Expect((&Policy{Rules: []PolicyRule{{Path: "a"}}}).Validate(nil)).ToNot(Succeed())
+		Expect((&Policy{Rules: []PolicyRule{{Path: "a", Strategy: "sum"}}}).Validate(nil)).To(Succeed())
+	})
+
+	It("is used by LoadPolicyFor", func() {
+		_, err := LoadPolicyFor(strings.NewReader("rules: [{path: nope, strategy: sum}]"), &policyConfig{})
+		Expect(err).To(MatchError(ContainSubstring(`path "nope" does not exist`)))
+	})
+})
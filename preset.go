@@ -0,0 +1,103 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PresetConfigLayering is an Option for merging layers of configuration, such as defaults,
+// a config file and environment overrides. Values in the source override those in the
+// target, maps and structs are merged recursively, and slices are replaced rather than
+// appended so that a layer can redefine a list.
+var PresetConfigLayering Option = func(o *Options) {
+	o.Overwrite = true
+	o.SetKindMergeFunc(reflect.Slice, mergeReplace)
+}
+
+// PresetJSONMergePatch is an Option for merging like a JSON merge patch (RFC 7386), as
+// decoded into maps. Maps are merged recursively and all other values, including slices,
+// are replaced. A nil value in a source map removes the key from the target.
+var PresetJSONMergePatch Option = func(o *Options) {
+	o.Overwrite = true
+	o.SetKindMergeFunc(reflect.Slice, mergeReplace)
+	o.SetKindMergeFunc(reflect.Map, mergeMapPatch)
+}
+
+// PresetNoClobber is an Option for only filling in what is missing from the target.
+// Values which are set in the target are never overwritten and slices are not appended
+// to, while zero values in the target are replaced and missing map keys are added.
+var PresetNoClobber Option = func(o *Options) {
+	o.Overwrite = false
+	o.SetKindMergeFunc(reflect.Slice, mergeFill)
+	o.SetDefaultMergeFunc(mergeFill)
+}
+
+// mergeFill returns the source if the target is a zero value or an empty slice, and the
+// target otherwise.
+func mergeFill(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.IsZero() || (t.Kind() == reflect.Slice && t.Len() == 0) {
+		return s, nil
+	}
+
+	return t, nil
+}
+
+// mergeMapPatch merges two maps like mergeMap, except that keys with nil values in the
+// source are removed from the result, including from maps nested in the source, and that
+// source values replace target values of another type, as in RFC 7386.
+func mergeMapPatch(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() != reflect.Map || s.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("got non-map type (tagret: %v; source: %v)", t.Kind(), s.Kind())
+	}
+
+	patched := reflect.MakeMapWithSize(t.Type(), t.Len())
+	iter := t.MapRange()
+	for iter.Next() {
+		patched.SetMapIndex(iter.Key(), iter.Value())
+	}
+	o.resolveCycles(t, s, patched)
+
+	iter = s.MapRange()
+	for iter.Next() {
+		if err := o.contextErr(); err != nil {
+			return reflect.Value{}, err
+		}
+
+		k, valS := iter.Key(), iter.Value()
+		if isEmpty(valS) || isEmpty(indirectValue(valS)) {
+			patched.SetMapIndex(k, reflect.Value{})
+			continue
+		}
+
+		valT := t.MapIndex(k)
+		src := indirectValue(valS)
+		switch {
+		case src.Kind() == reflect.Map:
+			// patch nested maps onto empty ones where the target holds no map of the same
+			// type, so that their nil values are removed as well
+			if !valT.IsValid() || indirectValue(valT).Type() != src.Type() {
+				valT = reflect.MakeMap(src.Type())
+			}
+
+		case valT.IsValid() && indirectValue(valT).Type() != src.Type():
+			// a value of another type replaces the target value
+			if err := checkChildSizes(valS, o); err != nil {
+				return reflect.Value{}, err
+			}
+			patched.SetMapIndex(k, valS)
+			continue
+		}
+
+		val, err := mergeChild(valT, valS, o, fmt.Sprint(k))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key '%s': %w", k, err)
+		}
+		patched.SetMapIndex(k, val)
+	}
+
+	if err := checkSize(reflect.Map, patched.Len(), o); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return patched, nil
+}
@@ -0,0 +1,88 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CycleError is returned when ErrorOnCycle is set and a merge encounters a reference
+// cycle, that is a pointer or map that refers back to itself.
+type CycleError struct {
+	// Type is the type of the value at which the cycle was detected
+	Type reflect.Type
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("reference cycle detected at value of type %v", e.Type)
+}
+
+// mergeState holds the state of a single merge as it recurses. It is created when a merge
+// starts and is shared by all merge funcs called during it, including nested calls to Merge.
+type mergeState struct {
+	// references that are currently being merged, used to detect cycles, along with the
+	// value that a reference back to them resolves to
+	visiting map[visitKey]reflect.Value
+
+	// results of references that have been merged, so that a pair of references reachable
+	// through several paths is only merged once and the result is shared
+	visited map[visitKey]reflect.Value
+
+	// the current recursion depth and the number of nodes visited so far
+	depth int
+	nodes int
+
+	// the path of the value currently being merged
+	path string
+}
+
+// visitKey identifies a pair of target and source references of a type.
+type visitKey struct {
+	target, source uintptr
+	typ            reflect.Type
+}
+
+func newMergeState() *mergeState {
+	return &mergeState{
+		visiting: map[visitKey]reflect.Value{},
+		visited:  map[visitKey]reflect.Value{},
+	}
+}
+
+// withState returns the options as is if they already carry the state of a merge, or a
+// shallow copy of them carrying a new state otherwise. Options are copied rather than
+// modified so that they can be shared between concurrent merges.
+func (o *Options) withState() *Options {
+	if o.state != nil {
+		return o
+	}
+
+	cp := *o
+	cp.state = newMergeState()
+	return &cp
+}
+
+// resolveCycles makes references back to the given pair, which is being merged, resolve to
+// val. Merge funcs that build their result in a copy of the target use this so that cycles
+// are preserved in the copy.
+func (o *Options) resolveCycles(valT, valS, val reflect.Value) {
+	if o.state == nil {
+		return
+	}
+
+	if key, ok := referenceKey(valT, valS); ok {
+		if _, visiting := o.state.visiting[key]; visiting {
+			o.state.visiting[key] = val
+		}
+	}
+}
+
+// referenceKey returns the key identifying a pair of non-nil pointers or maps. It reports
+// false for values that can not take part in a cycle.
+func referenceKey(valT, valS reflect.Value) (visitKey, bool) {
+	switch valT.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return visitKey{target: valT.Pointer(), source: valS.Pointer(), typ: valT.Type()}, true
+	}
+
+	return visitKey{}, false
+}
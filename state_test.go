@@ -0,0 +1,148 @@
+package conjungo
+
+import (
+	"errors"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reference cycles", func() {
+	type Node struct {
+		Name     string
+		Parent   *Node
+		Children []*Node
+	}
+
+	var (
+		opts           *Options
+		target, source *Node
+	)
+
+	BeforeEach(func() {
+		opts = NewOptions()
+		opts.PointerPolicy = PointerFollow
+
+		target = &Node{Name: "target-root"}
+		target.Children = []*Node{{Name: "target-child", Parent: target}}
+		target.Parent = target
+
+		source = &Node{Name: "source-root"}
+		source.Parent = source
+	})
+
+	It("preserves cycles by default", func() {
+		err := Merge(target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Name).To(Equal("source-root"))
+		Expect(target.Parent).To(BeIdenticalTo(target))
+		Expect(target.Children[0].Parent).To(BeIdenticalTo(target))
+	})
+
+	It("preserves cycles in newly allocated values", func() {
+		opts.PointerPolicy = PointerAllocate
+		original := target
+
+		err := Merge(&target, &source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).ToNot(BeIdenticalTo(original))
+		Expect(target.Name).To(Equal("source-root"))
+		Expect(target.Parent).To(BeIdenticalTo(target))
+		Expect(original.Name).To(Equal("target-root"))
+		Expect(original.Parent).To(BeIdenticalTo(original))
+	})
+
+	It("reports cycles with ErrorOnCycle", func() {
+		opts.ErrorOnCycle = true
+
+		err := Merge(target, source, opts)
+		Expect(err).To(HaveOccurred())
+
+		var cycleErr *CycleError
+		Expect(errors.As(err, &cycleErr)).To(BeTrue())
+		Expect(cycleErr.Type).To(Equal(reflect.TypeOf(&Node{})))
+		Expect(err.Error()).To(ContainSubstring("reference cycle detected at value of type *conjungo.Node"))
+	})
+
+	It("handles maps that contain themselves", func() {
+		t := map[string]interface{}{"name": "target"}
+		t["self"] = t
+		s := map[string]interface{}{"name": "source"}
+		s["self"] = s
+
+		err := Merge(&t, s, NewOptions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t["name"]).To(Equal("source"))
+		Expect(reflect.ValueOf(t["self"]).Pointer()).To(Equal(reflect.ValueOf(t).Pointer()))
+	})
+})
+
+var _ = Describe("shared references", func() {
+	type Limits struct {
+		Max  int
+		Tags []string
+	}
+
+	type Plan struct {
+		Read  *Limits
+		Write *Limits
+	}
+
+	It("merges a shared reference once and shares the result", func() {
+		shared := &Limits{Max: 1, Tags: []string{"a"}}
+		target := Plan{Read: shared, Write: shared}
+
+		sharedSource := &Limits{Max: 2, Tags: []string{"b"}}
+		source := Plan{Read: sharedSource, Write: sharedSource}
+
+		opts := NewOptions()
+		opts.PointerPolicy = PointerAllocate
+
+		err := Merge(&target, source, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Read).To(BeIdenticalTo(target.Write))
+		Expect(target.Read.Tags).To(Equal([]string{"a", "b"}))
+		Expect(shared.Tags).To(Equal([]string{"a"}))
+	})
+})
+
+var _ = Describe("withState", func() {
+	It("copies options without state", func() {
+		opts := NewOptions()
+		withState := opts.withState()
+
+		Expect(withState).ToNot(BeIdenticalTo(opts))
+		Expect(withState.state).ToNot(BeNil())
+		Expect(opts.state).To(BeNil())
+	})
+
+	It("reuses the state of a merge in progress", func() {
+		opts := NewOptions().withState()
+		Expect(opts.withState()).To(BeIdenticalTo(opts))
+	})
+
+	It("is shared with nested calls to Merge", func() {
+		type Wrapper struct {
+			Inner map[string]interface{}
+		}
+
+		var nestedState *mergeState
+		opts := NewOptions()
+		opts.SetTypeMergeFunc(reflect.TypeOf(Wrapper{}),
+			func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+				inner := t.Interface().(Wrapper).Inner
+				err := Merge(&inner, s.Interface().(Wrapper).Inner, o)
+				nestedState = o.state
+				return reflect.ValueOf(Wrapper{Inner: inner}), err
+			},
+		)
+
+		target := Wrapper{Inner: map[string]interface{}{"a": 1}}
+		err := Merge(&target, Wrapper{Inner: map[string]interface{}{"b": 2}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Inner).To(Equal(map[string]interface{}{"a": 1, "b": 2}))
+		Expect(nestedState).ToNot(BeNil())
+		Expect(opts.state).To(BeNil())
+	})
+})
@@ -0,0 +1,128 @@
+package conjungo
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// stdlibTypeFuncs returns the default merge funcs for well-known standard library types,
+// which are merged as single values rather than by kind. Their zero values are treated as
+// unset: an unset source keeps the target, and an unset target takes the source. Otherwise
+// the source replaces the target according to Overwrite. Merge funcs set for the types
+// themselves, an interface they implement or their kind take precedence over these.
+func stdlibTypeFuncs() map[reflect.Type]MergeFunc {
+	funcs := map[reflect.Type]MergeFunc{
+		timeType:                         mergeTime,
+		reflect.TypeOf(time.Duration(0)): mergeUnlessZero,
+		reflect.TypeOf(url.URL{}):        mergeUnlessZero,
+		reflect.TypeOf(net.IP{}):         mergeUnlessZero,
+		reflect.TypeOf(net.IPNet{}):      mergeUnlessZero,
+		reflect.TypeOf(big.Int{}):        mergeBig,
+		reflect.TypeOf(big.Float{}):      mergeBig,
+		reflect.TypeOf(big.Rat{}):        mergeBig,
+	}
+
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(sql.NullString{}),
+		reflect.TypeOf(sql.NullInt64{}),
+		reflect.TypeOf(sql.NullInt32{}),
+		reflect.TypeOf(sql.NullInt16{}),
+		reflect.TypeOf(sql.NullByte{}),
+		reflect.TypeOf(sql.NullFloat64{}),
+		reflect.TypeOf(sql.NullBool{}),
+		reflect.TypeOf(sql.NullTime{}),
+	} {
+		funcs[t] = mergeNullable
+	}
+
+	return funcs
+}
+
+// mergeUnlessZero merges two values of a type whose zero value means unset.
+func mergeUnlessZero(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeSet(t, s, t.IsZero(), s.IsZero(), o)
+}
+
+// mergeTime merges two time.Time values, of which the zero time means unset.
+func mergeTime(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeSet(t, s, isZeroTime(t), isZeroTime(s), o)
+}
+
+// mergeNullable merges two sql.Null* values, which are unset unless Valid.
+func mergeNullable(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeSet(t, s, !t.FieldByName("Valid").Bool(), !s.FieldByName("Valid").Bool(), o)
+}
+
+// mergeBig merges two big.Int, big.Float or big.Rat values, of which the zero value means
+// unset. The result is a copy, so that it does not share memory with the value merged.
+func mergeBig(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	merged, err := mergeSet(t, s, t.IsZero(), s.IsZero(), o)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	cp := reflect.New(merged.Type())
+	switch v := addressable(merged).Addr().Interface().(type) {
+	case *big.Int:
+		cp.Interface().(*big.Int).Set(v)
+	case *big.Float:
+		cp.Interface().(*big.Float).Copy(v)
+	case *big.Rat:
+		cp.Interface().(*big.Rat).Set(v)
+	default:
+		return reflect.Value{}, fmt.Errorf("can not copy %v", merged.Type())
+	}
+
+	return cp.Elem(), nil
+}
+
+// mergeSet merges two values given whether each of them is unset.
+func mergeSet(t, s reflect.Value, unsetT, unsetS bool, o *Options) (reflect.Value, error) {
+	switch {
+	case unsetS:
+		return t, nil
+	case unsetT:
+		return s, nil
+	}
+
+	return defaultMergeFunc(t, s, o)
+}
+
+// MergeLatestTime is a MergeFunc for time.Time which keeps the later of two times. A zero
+// time is never kept over another time.
+func MergeLatestTime(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeTimes(t, s, StrategyLatest, func(a, b time.Time) bool { return b.After(a) })
+}
+
+// MergeEarliestTime is a MergeFunc for time.Time which keeps the earlier of two times. A
+// zero time is never kept over another time.
+func MergeEarliestTime(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeTimes(t, s, StrategyEarliest, func(a, b time.Time) bool { return b.Before(a) })
+}
+
+// mergeTimes returns s if it is preferred over t.
+func mergeTimes(t, s reflect.Value, name string, prefer func(a, b time.Time) bool) (reflect.Value, error) {
+	if t.Type() != timeType {
+		return reflect.Value{}, fmt.Errorf("strategy %s requires time.Time, got %v", name, t.Type())
+	}
+
+	switch {
+	case isZeroTime(s):
+		return t, nil
+	case isZeroTime(t), prefer(t.Interface().(time.Time), s.Interface().(time.Time)):
+		return s, nil
+	}
+
+	return t, nil
+}
+
+func isZeroTime(v reflect.Value) bool {
+	return v.Interface().(time.Time).IsZero()
+}
@@ -0,0 +1,228 @@
+package conjungo
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("standard library types", func() {
+	earlier := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type record struct {
+		Created  time.Time
+		Timeout  time.Duration
+		Endpoint url.URL
+		Addr     net.IP
+		Name     sql.NullString
+		Count    sql.NullInt64
+		Seen     sql.NullTime
+		Balance  big.Int
+		Ratio    *big.Float
+	}
+
+	full := func() record {
+		return record{
+			Created:  earlier,
+			Timeout:  time.Second,
+			Endpoint: url.URL{Scheme: "https", Host: "a.example.com"},
+			Addr:     net.ParseIP("10.0.0.1"),
+			Name:     sql.NullString{String: "a", Valid: true},
+			Count:    sql.NullInt64{Int64: 1, Valid: true},
+			Seen:     sql.NullTime{Time: earlier, Valid: true},
+			Balance:  *big.NewInt(100),
+			Ratio:    big.NewFloat(0.5),
+		}
+	}
+
+	other := func() record {
+		return record{
+			Created:  later,
+			Timeout:  time.Minute,
+			Endpoint: url.URL{Scheme: "http", Host: "b.example.com", Path: "/b"},
+			Addr:     net.ParseIP("10.0.0.2"),
+			Name:     sql.NullString{String: "b", Valid: true},
+			Count:    sql.NullInt64{Int64: 2, Valid: true},
+			Seen:     sql.NullTime{Time: later, Valid: true},
+			Balance:  *big.NewInt(200),
+			Ratio:    big.NewFloat(0.25),
+		}
+	}
+
+	It("keeps the target where the source is unset", func() {
+		target := full()
+		err := Merge(&target, record{Name: sql.NullString{String: "ignored"}}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(earlier))
+		Expect(target.Timeout).To(Equal(time.Second))
+		Expect(target.Endpoint.Host).To(Equal("a.example.com"))
+		Expect(target.Addr.String()).To(Equal("10.0.0.1"))
+		Expect(target.Name).To(Equal(sql.NullString{String: "a", Valid: true}))
+		Expect(target.Count.Int64).To(Equal(int64(1)))
+		Expect(target.Seen.Time).To(Equal(earlier))
+		Expect(target.Balance.Int64()).To(Equal(int64(100)))
+		Expect(target.Ratio.String()).To(Equal("0.5"))
+	})
+
+	It("takes the source where the target is unset", func() {
+		target := record{}
+		opts := NewOptions(WithOverwrite(false))
+		err := Merge(&target, full(), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(earlier))
+		Expect(target.Timeout).To(Equal(time.Second))
+		Expect(target.Addr.String()).To(Equal("10.0.0.1"))
+		Expect(target.Count.Int64).To(Equal(int64(1)))
+		Expect(target.Balance.Int64()).To(Equal(int64(100)))
+	})
+
+	It("replaces set values as a whole according to Overwrite", func() {
+		target := full()
+		err := Merge(&target, other(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(later))
+		Expect(target.Endpoint).To(Equal(url.URL{Scheme: "http", Host: "b.example.com", Path: "/b"}))
+		Expect(target.Addr.String()).To(Equal("10.0.0.2"))
+		Expect(target.Name.String).To(Equal("b"))
+		Expect(target.Balance.Int64()).To(Equal(int64(200)))
+		Expect(target.Ratio.String()).To(Equal("0.25"))
+
+		target = full()
+		err = Merge(&target, other(), NewOptions(WithOverwrite(false)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(earlier))
+		Expect(target.Addr.String()).To(Equal("10.0.0.1"))
+		Expect(target.Name.String).To(Equal("a"))
+	})
+
+	It("does not error on unexported fields", func() {
+		opts := NewOptions()
+		opts.ErrorOnUnexported = true
+
+		target := full()
+		err := Merge(&target, other(), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(later))
+	})
+
+	It("copies big numbers", func() {
+		source := *big.NewInt(5)
+		target := big.Int{}
+
+		err := Merge(&target, source, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		target.Add(&target, big.NewInt(1))
+		Expect(source.Int64()).To(Equal(int64(5)))
+		Expect(target.Int64()).To(Equal(int64(6)))
+	})
+
+	It("follows pointers to big numbers", func() {
+		type holder struct {
+			Rat *big.Rat
+		}
+
+		opts := NewOptions(WithPointerPolicy(PointerFollow))
+		target := holder{Rat: big.NewRat(1, 2)}
+		ptr := target.Rat
+
+		err := Merge(&target, holder{Rat: big.NewRat(1, 3)}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Rat).To(BeIdenticalTo(ptr))
+		Expect(target.Rat.String()).To(Equal("1/3"))
+	})
+
+	DescribeTable("time strategies",
+		func(mf MergeFunc, target, source, expected time.Time) {
+			merged, err := mf(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(merged.Interface()).To(Equal(expected))
+		},
+		Entry("latest source", MergeLatestTime, earlier, later, later),
+		Entry("latest target", MergeLatestTime, later, earlier, later),
+		Entry("latest zero target", MergeLatestTime, time.Time{}, earlier, earlier),
+		Entry("latest zero source", MergeLatestTime, earlier, time.Time{}, earlier),
+		Entry("earliest source", MergeEarliestTime, later, earlier, earlier),
+		Entry("earliest target", MergeEarliestTime, earlier, later, earlier),
+		Entry("earliest zero target", MergeEarliestTime, time.Time{}, later, later),
+		Entry("earliest zero source", MergeEarliestTime, later, time.Time{}, later),
+	)
+
+	It("uses time strategies in tags", func() {
+		type window struct {
+			Start time.Time `conjungo:",strategy=earliest"`
+			End   time.Time `conjungo:",strategy=latest"`
+		}
+
+		target := window{Start: later, End: later}
+		err := Merge(&target, window{Start: earlier, End: earlier}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(window{Start: earlier, End: later}))
+	})
+
+	It("rejects other types in time strategies", func() {
+		_, err := MergeLatestTime(reflect.ValueOf(1), reflect.ValueOf(2), NewOptions())
+		Expect(err).To(MatchError("strategy latest requires time.Time, got int"))
+	})
+
+	It("can be overridden", func() {
+		opts := NewOptions(WithTypeFunc(reflect.TypeOf(time.Time{}), MergeLatestTime))
+
+		target := record{Created: later}
+		err := Merge(&target, record{Created: earlier}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(later))
+	})
+
+	It("merges durations with the merge func for their kind", func() {
+		opts := NewOptions()
+		opts.SetKindMergeFunc(reflect.Int64, MergeSum)
+
+		target := record{Timeout: time.Second}
+		err := Merge(&target, record{Timeout: time.Second}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Timeout).To(Equal(2 * time.Second))
+	})
+
+	It("uses merge funcs set for interfaces the types implement", func() {
+		var merged []reflect.Type
+		opts := NewOptions(WithTypeFunc(reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
+			func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+				merged = append(merged, t.Type())
+				return t, nil
+			}))
+
+		target := full()
+		err := Merge(&target, other(), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Created).To(Equal(earlier))
+		Expect(target.Timeout).To(Equal(time.Second))
+		Expect(target.Addr.String()).To(Equal("10.0.0.1"))
+		Expect(merged).To(ContainElements(
+			reflect.TypeOf(time.Time{}), reflect.TypeOf(time.Duration(0)), reflect.TypeOf(net.IP{})))
+	})
+
+	DescribeTable("uses merge funcs set for their kind",
+		func(kind reflect.Kind, target, source interface{}) {
+			opts := NewOptions(WithKindFunc(kind, mergeKeep))
+
+			targets := map[string]interface{}{"a": target}
+			err := Merge(&targets, map[string]interface{}{"a": source}, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets["a"]).To(Equal(target))
+		},
+		Entry("time.Time", reflect.Struct, earlier, later),
+		Entry("url.URL", reflect.Struct, url.URL{Host: "a.example.com"}, url.URL{Host: "b.example.com"}),
+		Entry("net.IP", reflect.Slice, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")),
+		Entry("time.Duration", reflect.Int64, time.Second, time.Minute),
+	)
+})
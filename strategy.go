@@ -0,0 +1,270 @@
+package conjungo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Built-in strategies, which can be referenced by name. See LookupStrategy.
+const (
+	// StrategyReplace replaces the target with the source, regardless of Overwrite.
+	StrategyReplace = "replace"
+
+	// StrategyKeep keeps the target, regardless of Overwrite.
+	StrategyKeep = "keep"
+
+	// StrategyDeep merges with the merge func for the kind of the values, or the default
+	// merge func, ignoring any merge funcs set for their type. This is the default
+	// behavior of merging maps, slices, arrays, structs and pointers.
+	StrategyDeep = "deep"
+
+	// StrategyIntersect, StrategySubtract and StrategySymmetricDifference treat maps as
+	// sets of their keys and keep the keys in both maps, those of the target that are not
+	// in the source, and those in either map but not in both. See MergeMapIntersect.
+	StrategyIntersect           = "intersect"
+	StrategySubtract            = "subtract"
+	StrategySymmetricDifference = "symdiff"
+
+	// StrategyTargetKeys only merges the keys of the source map that are in the target map.
+	// See MergeMapTargetKeys.
+	StrategyTargetKeys = "targetkeys"
+
+	// StrategyNewKeys only adds the keys of the source map that are not in the target map.
+	// See MergeMapNewKeys.
+	StrategyNewKeys = "newkeys"
+
+	// StrategyAppend appends the source slice to the target slice.
+	StrategyAppend = "append"
+
+	// StrategyUnion appends the elements of the source slice that are not already in the
+	// target slice, or merges maps as sets with MergeMapUnion. Elements are compared with
+	// reflect.DeepEqual.
+	StrategyUnion = "union"
+
+	// StrategyConcat concatenates strings or slices.
+	StrategyConcat = "concat"
+
+	// StrategyNonEmpty keeps the target string if the source is empty. See
+	// MergeNonEmptyString.
+	StrategyNonEmpty = "nonempty"
+
+	// StrategyTemplate replaces TemplateTarget in the source string with the target. See
+	// MergeStringTemplate.
+	StrategyTemplate = "template"
+
+	// StrategyJoinPath joins a relative source path onto the target path. See MergeJoinPath.
+	StrategyJoinPath = "joinpath"
+
+	// StrategyWords, StrategyCSV and StrategyPathList join lists of unique tokens separated
+	// by white space, commas or os.PathListSeparator, such as CSS classes, label selectors
+	// or PATH. See JoinUniqueTokens.
+	StrategyWords    = "words"
+	StrategyCSV      = "csv"
+	StrategyPathList = "pathlist"
+
+	// StrategyLatest and StrategyEarliest keep the later or earlier of two times. See
+	// MergeLatestTime and MergeEarliestTime.
+	StrategyLatest   = "latest"
+	StrategyEarliest = "earliest"
+
+	// StrategySum adds numbers. See MergeSum.
+	StrategySum = "sum"
+
+	// StrategyAverage averages numbers. See MergeAverage.
+	StrategyAverage = "average"
+
+	// StrategyMax keeps the greater of two numbers or strings. See MergeMax.
+	StrategyMax = "max"
+
+	// StrategyMin keeps the lesser of two numbers or strings. See MergeMin.
+	StrategyMin = "min"
+)
+
+var strategies = struct {
+	sync.RWMutex
+	funcs map[string]MergeFunc
+}{
+	funcs: map[string]MergeFunc{
+		StrategyReplace:             mergeReplace,
+		StrategyKeep:                mergeKeep,
+		StrategyDeep:                mergeDeep,
+		StrategyAppend:              mergeAppend,
+		StrategyUnion:               mergeUnion,
+		StrategyConcat:              mergeConcat,
+		StrategyNonEmpty:            MergeNonEmptyString,
+		StrategyTemplate:            MergeStringTemplate,
+		StrategyJoinPath:            MergeJoinPath,
+		StrategyWords:               JoinUniqueTokens(" "),
+		StrategyCSV:                 JoinUniqueTokens(","),
+		StrategyPathList:            JoinUniqueTokens(pathListSeparator),
+		StrategyIntersect:           MergeMapIntersect,
+		StrategySubtract:            MergeMapSubtract,
+		StrategySymmetricDifference: MergeMapSymmetricDifference,
+		StrategyTargetKeys:          MergeMapTargetKeys,
+		StrategyNewKeys:             MergeMapNewKeys,
+		StrategyLatest:              MergeLatestTime,
+		StrategyEarliest:            MergeEarliestTime,
+		StrategySum:                 MergeSum,
+		StrategyAverage:             MergeAverage,
+		StrategyMax:                 MergeMax,
+		StrategyMin:                 MergeMin,
+	},
+}
+
+// RegisterStrategy registers a merge func under a name, so that it can be referenced by
+// name from struct tags, such as `conjungo:",strategy=name"`. It panics if the name is
+// empty or already registered, or if the merge func is nil.
+func RegisterStrategy(name string, mf MergeFunc) {
+	if name == "" || mf == nil {
+		panic("conjungo: RegisterStrategy requires a name and a merge func")
+	}
+
+	strategies.Lock()
+	defer strategies.Unlock()
+
+	if _, ok := strategies.funcs[name]; ok {
+		panic(fmt.Sprintf("conjungo: strategy %q is already registered", name))
+	}
+
+	strategies.funcs[name] = mf
+}
+
+// LookupStrategy returns the merge func of a registered strategy.
+func LookupStrategy(name string) (MergeFunc, bool) {
+	strategies.RLock()
+	defer strategies.RUnlock()
+
+	mf, ok := strategies.funcs[name]
+	return mf, ok
+}
+
+// Strategies returns the names of all registered strategies, sorted.
+func Strategies() []string {
+	strategies.RLock()
+	defer strategies.RUnlock()
+
+	names := make([]string, 0, len(strategies.funcs))
+	for name := range strategies.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// strategy returns the merge func of a registered strategy, or an error if there is none.
+func strategy(name string) (MergeFunc, error) {
+	mf, ok := LookupStrategy(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown merge strategy %q", name)
+	}
+
+	return mf, nil
+}
+
+// WithTypeStrategy sets a registered strategy as the merge func for a type. The strategy
+// must be registered before the options are built.
+func WithTypeStrategy(t reflect.Type, name string) Option {
+	return func(o *Options) {
+		o.SetTypeMergeFunc(t, mustStrategy(name))
+	}
+}
+
+// WithKindStrategy sets a registered strategy as the merge func for a kind. The strategy
+// must be registered before the options are built.
+func WithKindStrategy(k reflect.Kind, name string) Option {
+	return func(o *Options) {
+		o.SetKindMergeFunc(k, mustStrategy(name))
+	}
+}
+
+func mustStrategy(name string) MergeFunc {
+	mf, err := strategy(name)
+	if err != nil {
+		panic("conjungo: " + err.Error())
+	}
+
+	return mf
+}
+
+func mergeReplace(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return s, nil
+}
+
+func mergeKeep(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return t, nil
+}
+
+func mergeDeep(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	o.mergeFuncs.mu.RLock()
+	mf, ok := o.mergeFuncs.kindFuncs[t.Kind()]
+	if !ok {
+		mf = o.mergeFuncs.defaultFunc
+	}
+	o.mergeFuncs.mu.RUnlock()
+
+	if mf == nil {
+		mf = defaultMergeFunc
+	}
+
+	return mf(t, s, o)
+}
+
+func mergeAppend(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if t.Kind() != reflect.Slice {
+		return reflect.Value{}, fmt.Errorf("strategy %s requires slices, got %v", StrategyAppend, t.Type())
+	}
+
+	return mergeSlice(t, s, o)
+}
+
+func mergeUnion(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Slice:
+		union := reflect.MakeSlice(t.Type(), 0, t.Len()+s.Len())
+		union = reflect.AppendSlice(union, t)
+
+		for i := 0; i < s.Len(); i++ {
+			if !containsValue(union, s.Index(i)) {
+				union = reflect.Append(union, s.Index(i))
+			}
+		}
+
+		if err := checkSize(reflect.Slice, union.Len(), o); err != nil {
+			return reflect.Value{}, err
+		}
+
+		return union, nil
+
+	case reflect.Map:
+		return MergeMapUnion(t, s, o)
+	}
+
+	return reflect.Value{}, fmt.Errorf("strategy %s requires slices or maps, got %v", StrategyUnion, t.Type())
+}
+
+func containsValue(slice, v reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		if reflect.DeepEqual(slice.Index(i).Interface(), v.Interface()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mergeConcat(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.String:
+		concat := reflect.New(t.Type()).Elem()
+		concat.SetString(t.String() + s.String())
+		return concat, nil
+
+	case reflect.Slice:
+		return mergeSlice(t, s, o)
+	}
+
+	return reflect.Value{}, fmt.Errorf("strategy %s requires strings or slices, got %v", StrategyConcat, t.Type())
+}
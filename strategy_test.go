@@ -0,0 +1,258 @@
+package conjungo
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("strategy registry", func() {
+	It("has the built-in strategies", func() {
+		Expect(Strategies()).To(ContainElements(
+			"replace", "keep", "deep", "append", "union", "concat", "sum", "average", "max", "min"))
+	})
+
+	It("registers strategies", func() {
+		RegisterStrategy("test-first", func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+			return t, nil
+		})
+
+		mf, ok := LookupStrategy("test-first")
+		Expect(ok).To(BeTrue())
+		Expect(mf).ToNot(BeNil())
+		Expect(Strategies()).To(ContainElement("test-first"))
+	})
+
+	It("panics on invalid registrations", func() {
+		Expect(func() { RegisterStrategy("replace", mergeKeep) }).To(Panic())
+		Expect(func() { RegisterStrategy("", mergeKeep) }).To(Panic())
+		Expect(func() { RegisterStrategy("test-nil", nil) }).To(Panic())
+	})
+
+	It("does not find unknown strategies", func() {
+		_, ok := LookupStrategy("nope")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("built-in strategies", func() {
+	type name string
+
+	DescribeTable("merge values",
+		func(strategy string, target, source, expected interface{}) {
+			mf, ok := LookupStrategy(strategy)
+			Expect(ok).To(BeTrue())
+
+			merged, err := mf(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(merged.Interface()).To(Equal(expected))
+		},
+		Entry("replace", "replace", 1, 2, 2),
+		Entry("keep", "keep", 1, 2, 1),
+		Entry("deep maps", "deep", map[string]int{"a": 1}, map[string]int{"b": 2}, map[string]int{"a": 1, "b": 2}),
+		Entry("deep scalars", "deep", 1, 2, 2),
+		Entry("append", "append", []int{1, 2}, []int{2, 3}, []int{1, 2, 2, 3}),
+		Entry("union slices", "union", []int{1, 2}, []int{2, 3, 3}, []int{1, 2, 3}),
+		Entry("union maps", "union", map[string]int{"a": 1}, map[string]int{"a": 2, "b": 2}, map[string]int{"a": 2, "b": 2}),
+		Entry("concat strings", "concat", name("a"), name("b"), name("ab")),
+		Entry("concat slices", "concat", []string{"a"}, []string{"b"}, []string{"a", "b"}),
+		Entry("sum ints", "sum", int8(1), int8(2), int8(3)),
+		Entry("sum uints", "sum", uint(1), uint(2), uint(3)),
+		Entry("sum floats", "sum", 1.5, 2.0, 3.5),
+		Entry("max ints", "max", 1, 2, 2),
+		Entry("max floats", "max", 2.5, 1.0, 2.5),
+		Entry("max strings", "max", "a", "b", "b"),
+		Entry("min uints", "min", uint(2), uint(1), uint(1)),
+		Entry("min strings", "min", "a", "b", "a"),
+	)
+
+	DescribeTable("reject unsupported types",
+		func(strategy string, value interface{}, msg string) {
+			mf, _ := LookupStrategy(strategy)
+
+			_, err := mf(reflect.ValueOf(value), reflect.ValueOf(value), NewOptions())
+			Expect(err).To(MatchError(msg))
+		},
+		Entry("append", "append", "a", "strategy append requires slices, got string"),
+		Entry("union", "union", 1, "strategy union requires slices or maps, got int"),
+		Entry("concat", "concat", 1, "strategy concat requires strings or slices, got int"),
+		Entry("sum", "sum", "a", "strategy sum requires numbers, got string"),
+		Entry("max", "max", true, "strategy max requires numbers or strings, got bool"),
+		Entry("min", "min", []int{}, "strategy min requires numbers or strings, got []int"),
+	)
+
+	It("ignores type funcs with deep", func() {
+		opts := NewOptions(WithTypeFunc(reflect.TypeOf(map[string]int{}), erroringMergeFunc))
+
+		merged, err := mergeDeep(reflect.ValueOf(map[string]int{"a": 1}), reflect.ValueOf(map[string]int{"a": 2}), opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged.Interface()).To(Equal(map[string]int{"a": 2}))
+	})
+
+	It("honors limits with union", func() {
+		opts := NewOptions()
+		opts.MaxSliceLen = 2
+
+		_, err := mergeUnion(reflect.ValueOf([]int{1, 2}), reflect.ValueOf([]int{3}), opts)
+		Expect(err).To(BeAssignableToTypeOf(&LimitError{}))
+	})
+})
+
+var _ = Describe("strategies in struct tags", func() {
+	type Stats struct {
+		Hits    int               `conjungo:",strategy=sum"`
+		Peak    int               `conjungo:"peak,strategy=max"`
+		Tags    []string          `conjungo:",strategy=union"`
+		Hosts   []string          `conjungo:",strategy=replace"`
+		Labels  map[string]string `conjungo:",strategy=keep"`
+		Comment string
+	}
+
+	It("merges fields with the strategy from their tag", func() {
+		target := Stats{
+			Hits: 2, Peak: 5, Tags: []string{"a"}, Hosts: []string{"x"},
+			Labels: map[string]string{"a": "1"}, Comment: "target",
+		}
+		source := Stats{
+			Hits: 3, Peak: 4, Tags: []string{"a", "b"}, Hosts: []string{"y"},
+			Labels: map[string]string{"b": "2"}, Comment: "source",
+		}
+
+		err := Merge(&target, source, NewOptions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(Stats{
+			Hits: 5, Peak: 5, Tags: []string{"a", "b"}, Hosts: []string{"y"},
+			Labels: map[string]string{"a": "1"}, Comment: "source",
+		}))
+	})
+
+	It("takes precedence over type funcs", func() {
+		opts := NewOptions(WithTypeFunc(reflect.TypeOf(0), erroringMergeFunc))
+		opts.SetTypeMergeFunc(reflect.TypeOf(""), mergeKeep)
+
+		target := Stats{Hits: 1, Peak: 1}
+		err := Merge(&target, Stats{Hits: 1, Peak: 2}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Hits).To(Equal(2))
+		Expect(target.Peak).To(Equal(2))
+	})
+
+	It("errors on unknown strategies", func() {
+		type Bad struct {
+			Value int `conjungo:",strategy=nope"`
+		}
+
+		target := Bad{}
+		err := Merge(&target, Bad{Value: 1}, NewOptions())
+		Expect(err).To(MatchError("failed to merge field `Bad.Value`: unknown merge strategy \"nope\""))
+	})
+})
+
+var _ = Describe("strategy Options", func() {
+	It("sets strategies for types and kinds", func() {
+		opts := NewOptions(
+			WithKindStrategy(reflect.Slice, StrategyUnion),
+			WithTypeStrategy(reflect.TypeOf(0), StrategySum),
+		)
+
+		target := map[string]interface{}{"n": 1, "s": []int{1, 2}}
+		err := Merge(&target, map[string]interface{}{"n": 2, "s": []int{2, 3}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{"n": 3, "s": []int{1, 2, 3}}))
+	})
+
+	It("panics on unknown strategies", func() {
+		Expect(func() { NewOptions(WithKindStrategy(reflect.Slice, "nope")) }).To(Panic())
+	})
+})
+
+var _ = Describe("presets", func() {
+	type Server struct {
+		Host  string
+		Port  int
+		Tags  []string
+		Extra map[string]string
+	}
+
+	It("layers configuration", func() {
+		opts := NewOptions(PresetConfigLayering)
+		target := Server{Host: "default", Port: 80, Tags: []string{"a"}, Extra: map[string]string{"a": "1"}}
+
+		err := Merge(&target, Server{Host: "override", Tags: []string{"b"}, Extra: map[string]string{"b": "2"}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(Server{
+			Host: "override", Port: 0, Tags: []string{"b"}, Extra: map[string]string{"a": "1", "b": "2"},
+		}))
+	})
+
+	It("applies JSON merge patches", func() {
+		opts := NewOptions(PresetJSONMergePatch)
+		target := map[string]interface{}{
+			"title":  "Goodbye!",
+			"author": map[string]interface{}{"givenName": "John", "familyName": "Doe"},
+			"tags":   []interface{}{"example", "sample"},
+		}
+		patch := map[string]interface{}{
+			"title":     "Hello!",
+			"phone":     "+01-123-456-7890",
+			"author":    map[string]interface{}{"familyName": nil},
+			"tags":      []interface{}{"example"},
+			"nested":    map[string]interface{}{"a": 1, "b": nil},
+			"nonexists": nil,
+		}
+
+		err := Merge(&target, patch, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{
+			"title":  "Hello!",
+			"author": map[string]interface{}{"givenName": "John"},
+			"tags":   []interface{}{"example"},
+			"phone":  "+01-123-456-7890",
+			"nested": map[string]interface{}{"a": 1},
+		}))
+	})
+
+	It("replaces values of another type in JSON merge patches", func() {
+		opts := NewOptions(PresetJSONMergePatch)
+		target := map[string]interface{}{
+			"a": map[string]interface{}{"b": 1},
+			"c": "x",
+			"d": []interface{}{1},
+		}
+		patch := map[string]interface{}{
+			"a": "x",
+			"c": map[string]interface{}{"e": 1, "f": nil},
+			"d": map[string]interface{}{"g": 2},
+		}
+
+		err := Merge(&target, patch, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]interface{}{
+			"a": "x",
+			"c": map[string]interface{}{"e": 1},
+			"d": map[string]interface{}{"g": 2},
+		}))
+	})
+
+	It("does not clobber values", func() {
+		opts := NewOptions(PresetNoClobber)
+		target := Server{Host: "target", Tags: []string{"a"}, Extra: map[string]string{"a": "1"}}
+
+		err := Merge(&target, Server{Host: "source", Port: 80, Tags: []string{"b"}, Extra: map[string]string{"a": "2", "b": "2"}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(Server{
+			Host: "target", Port: 80, Tags: []string{"a"}, Extra: map[string]string{"a": "1", "b": "2"},
+		}))
+	})
+
+	It("composes with other options", func() {
+		opts := NewOptions(PresetConfigLayering, WithKindStrategy(reflect.Slice, StrategyAppend))
+		target := Server{Tags: []string{"a"}}
+
+		err := Merge(&target, Server{Tags: []string{"b"}}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Tags).To(Equal([]string{"a", "b"}))
+	})
+})
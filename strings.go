@@ -0,0 +1,118 @@
+package conjungo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// TemplateTarget is replaced by the target value in source strings merged with
+// MergeStringTemplate.
+const TemplateTarget = "{{target}}"
+
+// MergeNonEmptyString is a MergeFunc for strings which keeps the target if the source is
+// empty, and uses the source otherwise.
+func MergeNonEmptyString(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if err := requireStrings(t, StrategyNonEmpty); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if s.Len() == 0 {
+		return t, nil
+	}
+	return s, nil
+}
+
+// MergeStringTemplate is a MergeFunc for strings which uses the source with each occurrence
+// of TemplateTarget replaced by the target. For instance, merging "/usr/bin" with
+// "/opt/bin:{{target}}" results in "/opt/bin:/usr/bin". A source without TemplateTarget
+// replaces the target.
+func MergeStringTemplate(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if err := requireStrings(t, StrategyTemplate); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return stringValue(t.Type(), strings.ReplaceAll(s.String(), TemplateTarget, t.String())), nil
+}
+
+// MergeJoinPath is a MergeFunc for strings which joins a relative source path onto the
+// target path, as with filepath.Join. An absolute source path replaces the target.
+func MergeJoinPath(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	if err := requireStrings(t, StrategyJoinPath); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if filepath.IsAbs(s.String()) || t.Len() == 0 {
+		return s, nil
+	}
+	return stringValue(t.Type(), filepath.Join(t.String(), s.String())), nil
+}
+
+// ConcatStrings returns a MergeFunc for strings which joins the target and source with the
+// given separator. If either is empty, the other is used as is.
+func ConcatStrings(sep string) MergeFunc {
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		if err := requireStrings(t, StrategyConcat); err != nil {
+			return reflect.Value{}, err
+		}
+
+		switch {
+		case s.Len() == 0:
+			return t, nil
+		case t.Len() == 0:
+			return s, nil
+		}
+		return stringValue(t.Type(), t.String()+sep+s.String()), nil
+	}
+}
+
+// JoinUniqueTokens returns a MergeFunc for strings which are lists of tokens separated by
+// sep, such as comma separated lists or CSS classes. The tokens of the source which are not
+// in the target are appended to it, in order. Tokens are trimmed of spaces and empty ones
+// are dropped. If sep is only made of white space, tokens are separated by any white space.
+func JoinUniqueTokens(sep string) MergeFunc {
+	split := func(str string) []string {
+		return strings.Split(str, sep)
+	}
+	if strings.TrimSpace(sep) == "" {
+		split = strings.Fields
+	}
+
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		if t.Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("joining tokens requires strings, got %v", t.Type())
+		}
+
+		seen := map[string]bool{}
+		var tokens []string
+		for _, str := range []string{t.String(), s.String()} {
+			for _, token := range split(str) {
+				token = strings.TrimSpace(token)
+				if token != "" && !seen[token] {
+					seen[token] = true
+					tokens = append(tokens, token)
+				}
+			}
+		}
+
+		return stringValue(t.Type(), strings.Join(tokens, sep)), nil
+	}
+}
+
+func requireStrings(t reflect.Value, name string) error {
+	if t.Kind() != reflect.String {
+		return fmt.Errorf("strategy %s requires strings, got %v", name, t.Type())
+	}
+	return nil
+}
+
+// stringValue returns str as a value of the given string type.
+func stringValue(t reflect.Type, str string) reflect.Value {
+	v := reflect.New(t).Elem()
+	v.SetString(str)
+	return v
+}
+
+var pathListSeparator = string(os.PathListSeparator)
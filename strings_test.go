@@ -0,0 +1,93 @@
+package conjungo
+
+import (
+	"path/filepath"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("string merge funcs", func() {
+	type label string
+
+	DescribeTable("merge strings",
+		func(mf MergeFunc, target, source, expected interface{}) {
+			Expect(applyMergeFunc(mf, target, source)).To(Equal(expected))
+		},
+		Entry("non-empty source", MergeNonEmptyString, "a", "b", "b"),
+		Entry("empty source", MergeNonEmptyString, "a", "", "a"),
+		Entry("empty target", MergeNonEmptyString, "", "b", "b"),
+
+		Entry("template", MergeStringTemplate, "/usr/bin", "/opt/bin:{{target}}", "/opt/bin:/usr/bin"),
+		Entry("repeated template", MergeStringTemplate, "a", "{{target}}-{{target}}", "a-a"),
+		Entry("no template", MergeStringTemplate, "a", "b", "b"),
+		Entry("named template", MergeStringTemplate, label("a"), label("{{target}}b"), label("ab")),
+
+		Entry("relative path", MergeJoinPath, "/etc/app", "conf.d/../app.yaml", filepath.Join("/etc/app", "app.yaml")),
+		Entry("absolute path", MergeJoinPath, "/etc/app", "/var/app", "/var/app"),
+		Entry("empty target path", MergeJoinPath, "", "app", "app"),
+
+		Entry("concat", ConcatStrings(", "), "a", "b", "a, b"),
+		Entry("concat empty source", ConcatStrings(", "), "a", "", "a"),
+		Entry("concat empty target", ConcatStrings(", "), "", "b", "b"),
+		Entry("concat named", ConcatStrings("-"), label("a"), label("b"), label("a-b")),
+
+		Entry("csv tokens", JoinUniqueTokens(","), "a,b", "b, c,,a", "a,b,c"),
+		Entry("selector tokens", JoinUniqueTokens(","), "app=web", "tier=front,app=web", "app=web,tier=front"),
+		Entry("path tokens", JoinUniqueTokens(":"), "/usr/bin:/bin", "/opt/bin:/bin", "/usr/bin:/bin:/opt/bin"),
+		Entry("class tokens", JoinUniqueTokens(" "), "btn  btn-primary", "\tbtn active\n", "btn btn-primary active"),
+		Entry("empty tokens", JoinUniqueTokens(","), "", "", ""),
+	)
+
+	DescribeTable("reject other kinds",
+		func(mf MergeFunc, msg string) {
+			_, err := applyMergeFunc(mf, 1, 2)
+			Expect(err).To(MatchError(msg))
+		},
+		Entry("non-empty", MergeNonEmptyString, "strategy nonempty requires strings, got int"),
+		Entry("template", MergeStringTemplate, "strategy template requires strings, got int"),
+		Entry("path", MergeJoinPath, "strategy joinpath requires strings, got int"),
+		Entry("concat", ConcatStrings(","), "strategy concat requires strings, got int"),
+		Entry("tokens", JoinUniqueTokens(","), "joining tokens requires strings, got int"),
+	)
+
+	It("can be used in tags", func() {
+		type env struct {
+			Name     string `conjungo:",strategy=nonempty"`
+			Path     string `conjungo:",strategy=pathlist"`
+			Selector string `conjungo:",strategy=csv"`
+			Class    string `conjungo:",strategy=words"`
+			Home     string `conjungo:",strategy=joinpath"`
+			Prompt   string `conjungo:",strategy=template"`
+		}
+
+		sep := string(filepath.ListSeparator)
+		target := env{
+			Name: "app", Path: "/usr/bin" + sep + "/bin", Selector: "app=web",
+			Class: "btn", Home: "/home", Prompt: "$",
+		}
+		source := env{
+			Path: "/opt/bin" + sep + "/bin", Selector: "tier=front",
+			Class: "btn active", Home: "app", Prompt: "[app] {{target}}",
+		}
+
+		err := Merge(&target, source, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(env{
+			Name: "app", Path: "/usr/bin" + sep + "/bin" + sep + "/opt/bin", Selector: "app=web,tier=front",
+			Class: "btn active", Home: filepath.Join("/home", "app"), Prompt: "[app] $",
+		}))
+	})
+
+	It("can be set for the string kind", func() {
+		opts := NewOptions()
+		opts.SetKindMergeFunc(reflect.String, MergeNonEmptyString)
+
+		target := map[string]string{"a": "1", "b": "2"}
+		err := Merge(&target, map[string]string{"a": "", "b": "3"}, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(map[string]string{"a": "1", "b": "3"}))
+	})
+})
@@ -0,0 +1,296 @@
+package conjungo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	yamlNodeType    = reflect.TypeOf(yaml.Node{})
+	yamlNodePtrType = reflect.TypeOf(&yaml.Node{})
+)
+
+// mergeYAMLNode merges two yaml.Node trees, such as documents decoded with yaml.Unmarshal
+// into a yaml.Node, keeping the comments, anchors, style and positions of the target.
+//
+// Mappings are merged key by key and sequences are appended, as maps and slices are by
+// default. Scalars are decoded and merged with the merge func for their type, so that
+// merge funcs and policy rules for types or kinds apply to them, as do those for maps and
+// slices in place of the defaults. A null source leaves the target as is.
+func mergeYAMLNode(t, s reflect.Value, o *Options) (reflect.Value, error) {
+	return mergeYAMLNodeWith(nil)(t, s, o)
+}
+
+// mergeYAMLNodeWith returns a merge func for yaml.Node values which merges them with the
+// given merge func, such as a strategy, by decoding them. If mf is nil, they are merged as
+// described for mergeYAMLNode.
+func mergeYAMLNodeWith(mf MergeFunc) MergeFunc {
+	return func(t, s reflect.Value, o *Options) (reflect.Value, error) {
+		nodeT := t.Interface().(yaml.Node)
+		nodeS := s.Interface().(yaml.Node)
+
+		merged, err := mergeNodes(&nodeT, &nodeS, o, mf)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return reflect.ValueOf(*merged), nil
+	}
+}
+
+// adaptStrategy returns the merge func adapted to merge values of the given type. Values of
+// most types are merged by the func as is, but yaml.Node trees are decoded for it.
+func adaptStrategy(mf MergeFunc, t reflect.Type) MergeFunc {
+	if mf == nil {
+		return nil
+	}
+
+	switch t {
+	case yamlNodeType:
+		return mergeYAMLNodeWith(mf)
+	case yamlNodePtrType:
+		return mergeElemsFunc(mergeYAMLNodeWith(mf))
+	}
+
+	return mf
+}
+
+// mergeNodes merges two nodes into a new node, leaving both as they are.
+func mergeNodes(t, s *yaml.Node, o *Options, mf MergeFunc) (*yaml.Node, error) {
+	if t.Kind == yaml.DocumentNode && s.Kind == yaml.DocumentNode &&
+		len(t.Content) == 1 && len(s.Content) == 1 {
+		content, err := mergeNodes(t.Content[0], s.Content[0], o, mf)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := *t
+		merged.Content = []*yaml.Node{content}
+		return &merged, nil
+	}
+
+	// read through aliases, while a target alias is replaced by the merged value
+	if s.Kind == yaml.AliasNode && s.Alias != nil {
+		s = s.Alias
+	}
+	valT := t
+	if t.Kind == yaml.AliasNode && t.Alias != nil {
+		valT = t.Alias
+	}
+
+	switch {
+	case isNullNode(s):
+		return t, nil
+	case isNullNode(valT) || valT.Kind == 0:
+		return withTargetMeta(s, t), nil
+	}
+
+	var merged *yaml.Node
+	var err error
+	switch {
+	case mf == nil && valT.Kind == yaml.MappingNode && s.Kind == yaml.MappingNode &&
+		sameFunc(nodeFunc(reflect.TypeOf(map[string]interface{}{}), o), mergeMap):
+		merged, err = mergeMappingNodes(valT, s, o)
+
+	case mf == nil && valT.Kind == yaml.SequenceNode && s.Kind == yaml.SequenceNode &&
+		sameFunc(nodeFunc(reflect.TypeOf([]interface{}{}), o), mergeSlice):
+		merged, err = mergeSequenceNodes(valT, s, o)
+
+	default:
+		merged, err = mergeDecodedNodes(valT, s, o, mf)
+	}
+	if err != nil {
+		return nil, atNode(s, err)
+	}
+	if merged == valT {
+		return t, nil
+	}
+
+	merged = withTargetMeta(merged, t)
+	if valT != t {
+		// the merged value replaces the alias, while the anchored value is left as is
+		merged.Anchor = ""
+	}
+
+	return merged, nil
+}
+
+// mergeMappingNodes merges two mapping nodes key by key, keeping the keys of the target in
+// order followed by the new keys of the source.
+func mergeMappingNodes(t, s *yaml.Node, o *Options) (*yaml.Node, error) {
+	merged := *t
+	merged.Content = append([]*yaml.Node(nil), t.Content...)
+
+	index := map[string]int{}
+	for i := 0; i+1 < len(t.Content); i += 2 {
+		index[t.Content[i].Value] = i + 1
+	}
+
+	for i := 0; i+1 < len(s.Content); i += 2 {
+		if err := o.contextErr(); err != nil {
+			return nil, err
+		}
+
+		key, valS := s.Content[i], s.Content[i+1]
+		j, ok := index[key.Value]
+		if !ok {
+			index[key.Value] = len(merged.Content) + 1
+			merged.Content = append(merged.Content, key, valS)
+			continue
+		}
+
+		val, err := mergeChild(reflect.ValueOf(merged.Content[j]), reflect.ValueOf(valS), o, key.Value)
+		if err != nil {
+			// errors raised before the value is merged, such as by hooks, have no position yet
+			return nil, fmt.Errorf("key '%s': %w", key.Value, atNode(valS, err))
+		}
+		if val.IsValid() {
+			merged.Content[j] = val.Interface().(*yaml.Node)
+		}
+	}
+
+	if err := checkSize(reflect.Map, len(merged.Content)/2, o); err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// mergeSequenceNodes appends the items of the source sequence to the target sequence.
+func mergeSequenceNodes(t, s *yaml.Node, o *Options) (*yaml.Node, error) {
+	if err := checkSize(reflect.Slice, len(t.Content)+len(s.Content), o); err != nil {
+		return nil, err
+	}
+
+	merged := *t
+	merged.Content = make([]*yaml.Node, 0, len(t.Content)+len(s.Content))
+	merged.Content = append(merged.Content, t.Content...)
+	merged.Content = append(merged.Content, s.Content...)
+
+	return &merged, nil
+}
+
+// mergeDecodedNodes merges the decoded values of two nodes with the given merge func, or
+// the merge func for their type if it is nil. It returns the target or source node if the
+// result is the value of either, and a new node otherwise.
+func mergeDecodedNodes(t, s *yaml.Node, o *Options, mf MergeFunc) (*yaml.Node, error) {
+	var decodedT, decodedS interface{}
+	if err := t.Decode(&decodedT); err != nil {
+		return nil, err
+	}
+	if err := s.Decode(&decodedS); err != nil {
+		return nil, err
+	}
+
+	valT, valS := reflect.ValueOf(decodedT), reflect.ValueOf(decodedS)
+
+	f := mf
+	switch {
+	case valT.Type() != valS.Type():
+		// values of different types, such as a mapping and a scalar, can only replace
+		// one another
+		f = defaultMergeFunc
+	case f == nil:
+		f = nodeFunc(valT.Type(), o)
+	}
+
+	merged, err := f(valT, valS, o)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !merged.IsValid():
+		return t, nil
+	case reflect.DeepEqual(merged.Interface(), decodedT):
+		return t, nil
+	case reflect.DeepEqual(merged.Interface(), decodedS):
+		return s, nil
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(merged.Interface()); err != nil {
+		return nil, err
+	}
+	if node.Tag == t.Tag {
+		node.Style = t.Style
+	}
+
+	return node, nil
+}
+
+// withTargetMeta returns a copy of the node with the position and anchor of the target,
+// and its comments where it has any. A mapping or sequence also keeps the block or flow
+// style of the target.
+func withTargetMeta(n, t *yaml.Node) *yaml.Node {
+	merged := *n
+	merged.Line, merged.Column = t.Line, t.Column
+
+	if n.Kind == t.Kind && n.Kind != yaml.ScalarNode {
+		merged.Style = t.Style
+	}
+
+	if t.Anchor != "" {
+		merged.Anchor = t.Anchor
+	}
+	if t.HeadComment != "" {
+		merged.HeadComment = t.HeadComment
+	}
+	if t.LineComment != "" {
+		merged.LineComment = t.LineComment
+	}
+	if t.FootComment != "" {
+		merged.FootComment = t.FootComment
+	}
+
+	return &merged
+}
+
+// nodeFunc returns the merge func for decoded values of the type, which is that of the
+// first type or kind rule of the policy matching it, or the one set for the type.
+func nodeFunc(t reflect.Type, o *Options) MergeFunc {
+	if o.policy != nil {
+		if f := o.policy.typeFuncFor(t); f != nil {
+			return f
+		}
+	}
+
+	return o.mergeFuncs.getFunc(reflect.Zero(t))
+}
+
+// nodeError is an error raised while merging a node, which reports the position of the
+// source node.
+type nodeError struct {
+	line, column int
+	err          error
+}
+
+func (e *nodeError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.line, e.column, e.err)
+}
+
+func (e *nodeError) Unwrap() error {
+	return e.err
+}
+
+// atNode adds the position of the node to the error, unless it already reports the
+// position of a node within it or the node has no position.
+func atNode(n *yaml.Node, err error) error {
+	var nodeErr *nodeError
+	if n.Line == 0 || errors.As(err, &nodeErr) {
+		return err
+	}
+
+	return &nodeError{line: n.Line, column: n.Column, err: err}
+}
+
+func isNullNode(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+func sameFunc(a, b MergeFunc) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
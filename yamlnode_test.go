@@ -0,0 +1,205 @@
+package conjungo
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+func parseYAML(doc string) yaml.Node {
+	var node yaml.Node
+	Expect(yaml.Unmarshal([]byte(doc), &node)).To(Succeed())
+	return node
+}
+
+func renderYAML(node *yaml.Node) string {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	Expect(enc.Encode(node)).To(Succeed())
+	return buf.String()
+}
+
+var _ = Describe("yaml.Node", func() {
+	const targetDoc = `# service config
+name: api # the service name
+server:
+  host: localhost
+  port: 8080
+defaults: &defaults
+  retries: 3
+tags:
+  - a
+`
+
+	It("merges documents keeping the comments and layout of the target", func() {
+		target := parseYAML(targetDoc)
+		source := parseYAML(`
+server:
+  port: 9090
+  # added
+  tls: true
+defaults:
+  timeout: 5s
+tags: [b]
+name: ~
+extra: "x"
+`)
+
+		Expect(Merge(&target, source, nil)).To(Succeed())
+		Expect(renderYAML(&target)).To(Equal(`# service config
+name: api # the service name
+server:
+  host: localhost
+  port: 9090
+  # added
+  tls: true
+defaults: &defaults
+  retries: 3
+  timeout: 5s
+tags:
+  - a
+  - b
+extra: "x"
+`))
+	})
+
+	It("keeps the position of target values", func() {
+		target := parseYAML(targetDoc)
+		Expect(Merge(&target, parseYAML("server: {port: 9090}"), nil)).To(Succeed())
+
+		port := target.Content[0].Content[3].Content[3]
+		Expect(port.Value).To(Equal("9090"))
+		Expect(port.Line).To(Equal(5))
+		Expect(port.Column).To(Equal(9))
+	})
+
+	It("does not change the merged trees", func() {
+		target := parseYAML(targetDoc)
+		source := parseYAML("server: {port: 9090}\ntags: [b]")
+
+		merged, err := mergeYAMLNode(reflect.ValueOf(target), reflect.ValueOf(source), NewOptions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renderYAML(&target)).To(Equal(renderYAML(ptr(parseYAML(targetDoc)))))
+		Expect(renderYAML(ptr(merged.Interface().(yaml.Node)))).To(ContainSubstring("port: 9090"))
+	})
+
+	It("keeps the target according to Overwrite", func() {
+		target := parseYAML(targetDoc)
+		opts := NewOptions()
+		opts.Overwrite = false
+
+		Expect(Merge(&target, parseYAML("name: web\nserver: {port: 9090, tls: true}"), opts)).To(Succeed())
+		Expect(renderYAML(&target)).To(ContainSubstring("name: api # the service name\n"))
+		Expect(renderYAML(&target)).To(ContainSubstring("  port: 8080\n  tls: true\n"))
+	})
+
+	It("applies merge funcs and strategies for kinds", func() {
+		target := parseYAML(targetDoc)
+		opts := NewOptions(WithKindStrategy(reflect.Int, StrategySum), WithKindStrategy(reflect.Slice, StrategyReplace))
+
+		Expect(Merge(&target, parseYAML("server: {port: 2}\ntags: [b, c]"), opts)).To(Succeed())
+		Expect(renderYAML(&target)).To(ContainSubstring("  port: 8082\n"))
+		Expect(renderYAML(&target)).To(ContainSubstring("tags:\n  - b\n  - c\n"))
+	})
+
+	It("applies policy rules by path and type", func() {
+		opts, err := LoadPolicy(strings.NewReader(`
+rules:
+  - path: server
+    conflict: keep
+  - path: tags
+    strategy: union
+  - type: int
+    strategy: max
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		target := parseYAML(targetDoc)
+		source := parseYAML("server: {host: example.com}\ntags: [a, b]\ndefaults: {retries: 5}")
+		Expect(Merge(&target, source, opts)).To(Succeed())
+
+		out := renderYAML(&target)
+		Expect(out).To(ContainSubstring("  host: localhost\n"))
+		Expect(out).To(ContainSubstring("  retries: 5\n"))
+		Expect(out).To(ContainSubstring("tags:\n  - a\n  - b\n"))
+	})
+
+	It("applies strategies from struct tags", func() {
+		type config struct {
+			Replaced *yaml.Node `conjungo:",strategy=replace"`
+			Summed   yaml.Node  `conjungo:",strategy=sum"`
+		}
+
+		replaced, summed := parseYAML("{a: 1}"), parseYAML("1")
+		target := config{Replaced: &replaced, Summed: summed}
+		source := config{Replaced: ptr(parseYAML("{b: 2}")), Summed: parseYAML("2")}
+
+		Expect(Merge(&target, source, nil)).To(Succeed())
+		Expect(renderYAML(target.Replaced)).To(Equal("{b: 2}\n"))
+		Expect(renderYAML(&target.Summed)).To(Equal("3\n"))
+		Expect(renderYAML(&replaced)).To(Equal("{a: 1}\n"))
+	})
+
+	It("replaces target aliases with the merged value", func() {
+		target := parseYAML("base: &base {a: 1}\nuse: *base\n")
+		Expect(Merge(&target, parseYAML("use: {b: 2}"), nil)).To(Succeed())
+		Expect(renderYAML(&target)).To(Equal("base: &base {a: 1}\nuse: {a: 1, b: 2}\n"))
+	})
+
+	It("reports the position of the source in errors", func() {
+		target := parseYAML(targetDoc)
+		opts := NewOptions(WithKindStrategy(reflect.String, StrategySum))
+
+		err := Merge(&target, parseYAML("server:\n  host: example.com\n"), opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("key 'server': key 'host': line 2, column 9: "))
+	})
+
+	It("enforces size limits", func() {
+		target := parseYAML("[a, b]")
+		opts := NewOptions()
+		opts.MaxSliceLen = 3
+
+		Expect(Merge(&target, parseYAML("[c, d]"), opts)).ToNot(Succeed())
+	})
+
+	It("reports the position of mappings and sequences in errors", func() {
+		target := parseYAML(targetDoc)
+		opts := NewOptions()
+		opts.MaxMapKeys = 2
+		opts.MaxSliceLen = 1
+
+		err := Merge(&target, parseYAML("server:\n  debug: true\n"), opts)
+		Expect(err).To(MatchError(ContainSubstring("key 'server': line 2, column 3: merge limit exceeded: MaxMapKeys")))
+
+		err = Merge(&target, parseYAML("name: api\ntags:\n  - b\n"), opts)
+		Expect(err).To(MatchError(ContainSubstring("key 'tags': line 3, column 3: merge limit exceeded: MaxSliceLen")))
+
+		var limitErr *LimitError
+		Expect(errors.As(err, &limitErr)).To(BeTrue())
+	})
+
+	It("reports the position of values in errors raised before they are merged", func() {
+		target := parseYAML(targetDoc)
+		opts := NewOptions()
+		opts.BeforeMerge = func(path string, t, s reflect.Value) (reflect.Value, reflect.Value, error) {
+			if path == "server.port" {
+				return t, s, errors.New("port is fixed")
+			}
+			return t, s, nil
+		}
+
+		err := Merge(&target, parseYAML("server:\n  port: 9090\n"), opts)
+		Expect(err).To(MatchError("key 'server': key 'port': line 2, column 9: port is fixed"))
+	})
+})
+
+func ptr[T any](v T) *T {
+	return &v
+}